@@ -0,0 +1,71 @@
+package fifo
+
+import "slices"
+
+// prefers t 是否与队伍有偏好关系：白名单中有对方的队员，或 AffinityArg 的值相同
+func (c *candidate) prefers(t *Ticket) bool {
+	for _, m := range t.Members {
+		if slices.Contains(c.likes, m.BlackId) {
+			return true
+		}
+	}
+	if intersects(t.WhiteList, c.me) {
+		return true
+	}
+	if c.pool.AffinityArg != "" {
+		if tag, ok := findString(t.StringArgs, c.pool.AffinityArg); ok && slices.Contains(c.tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// prefersMatch 队伍是否与 cs 中的某队 AffinityArg 的值相同
+func (c *candidate) prefersMatch(cs []*candidate) bool {
+	for _, c2 := range cs {
+		if intersects(c.tags, c2.tags) {
+			return true
+		}
+	}
+	return false
+}
+
+// preferences 统计一场匹配中满足的偏好数：
+// 每个 ticket 白名单中与其同队的 BlackId 各计 1，有 AffinityArg 的 ticket 与同场其它 ticket 值相同时计 1
+func preferences(teams []*candidate) int {
+	n := 0
+	for _, c := range teams {
+		for _, t := range c.tickets {
+			for _, id := range t.WhiteList {
+				if slices.ContainsFunc(c.tickets, func(t2 *Ticket) bool {
+					return t2 != t && slices.ContainsFunc(t2.Members, func(m Member) bool { return m.BlackId == id })
+				}) {
+					n++
+				}
+			}
+			if sharesTag(t, teams) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func sharesTag(t *Ticket, teams []*candidate) bool {
+	arg := teams[0].pool.AffinityArg
+	if arg == "" {
+		return false
+	}
+	tag, ok := findString(t.StringArgs, arg)
+	if !ok {
+		return false
+	}
+	for _, c := range teams {
+		for _, t2 := range c.tickets {
+			if tag2, ok := findString(t2.StringArgs, arg); ok && t2 != t && tag2 == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package fifo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FifoMatchWhiteList(t *testing.T) {
+	tickets := make(map[string]*Ticket)
+	for i, id := range []string{"a", "b", "c"} {
+		tickets[id] = &Ticket{TicketId: id, Members: []Member{{MemberId: id, BlackId: int64(i + 1)}}, startMatch: int64(i)}
+	}
+	tickets["a"].WhiteList = []int64{3}
+	pool := PoolProfile{Teams: []string{"1"}, TeamMembers: 2, MaxMatchPerRound: 10}
+	results, remaining := Match(pool, tickets, 10)
+	assert.Len(t, results, 1)
+	assert.Equal(t, []string{"a", "c"}, results[0].Teams[0].TicketId)
+	assert.Equal(t, 1, results[0].Preferred)
+	assert.Equal(t, []*Ticket{tickets["b"]}, remaining)
+}
+
+func Test_FifoMatchAffinity(t *testing.T) {
+	tickets := make(map[string]*Ticket)
+	for i, id := range []string{"a", "b", "c", "d"} {
+		tickets[id] = &Ticket{TicketId: id, Members: []Member{{MemberId: id, BlackId: int64(i + 1)}}, startMatch: int64(i)}
+	}
+	tickets["a"].StringArgs = []StringArg{{"party", "x"}}
+	tickets["c"].StringArgs = []StringArg{{"party", "x"}}
+	pool := PoolProfile{Teams: []string{"1", "2"}, TeamMembers: 1, MaxMatchPerRound: 10}
+	results, _ := Match(pool, tickets, 10)
+	assert.Equal(t, []string{"b"}, results[0].Teams[1].TicketId)
+
+	pool.AffinityArg = "party"
+	results, _ = Match(pool, tickets, 10)
+	assert.Len(t, results, 2)
+	assert.Equal(t, []string{"a"}, results[0].Teams[0].TicketId)
+	assert.Equal(t, []string{"c"}, results[0].Teams[1].TicketId)
+	assert.Equal(t, 2, results[0].Preferred)
+	assert.Equal(t, 0, results[1].Preferred)
+}
@@ -0,0 +1,55 @@
+package fifo
+
+import "sync"
+
+// MatchAlgorithm 匹配算法，签名与 FifoMatch 一致
+type MatchAlgorithm func(pool PoolProfile, tickets map[string]*Ticket, now int64, r ResultSubmitter)
+
+// AlgorithmRegistry 按 MatchProfile.Algorithm 查找匹配算法
+type AlgorithmRegistry interface {
+	Register(name string, a MatchAlgorithm)
+	Lookup(name string) (MatchAlgorithm, bool)
+}
+
+const (
+	AlgorithmFifo = "fifo"
+	AlgorithmMwm  = "mwm"
+)
+
+// DefaultRegistry 默认注册表，内置 fifo 与 mwm
+var DefaultRegistry AlgorithmRegistry = NewRegistry()
+
+// Register 向 DefaultRegistry 注册算法
+func Register(name string, a MatchAlgorithm) {
+	DefaultRegistry.Register(name, a)
+}
+
+type registry struct {
+	mu    sync.RWMutex
+	algos map[string]MatchAlgorithm
+}
+
+// NewRegistry 创建一个包含内置算法的注册表
+func NewRegistry() AlgorithmRegistry {
+	r := &registry{algos: make(map[string]MatchAlgorithm)}
+	r.Register(AlgorithmFifo, FifoMatch)
+	r.Register(AlgorithmMwm, MwmMatch)
+	return r
+}
+
+func (r *registry) Register(name string, a MatchAlgorithm) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.algos[name] = a
+}
+
+// Lookup 查找算法，空名字视为 fifo
+func (r *registry) Lookup(name string) (MatchAlgorithm, bool) {
+	if name == "" {
+		name = AlgorithmFifo
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	a, ok := r.algos[name]
+	return a, ok
+}
@@ -0,0 +1,54 @@
+package fifo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Registry(t *testing.T) {
+	r := NewRegistry()
+	_, ok := r.Lookup("")
+	assert.True(t, ok)
+	_, ok = r.Lookup(AlgorithmMwm)
+	assert.True(t, ok)
+	_, ok = r.Lookup("custom")
+	assert.False(t, ok)
+
+	called := false
+	r.Register("custom", func(PoolProfile, map[string]*Ticket, int64, ResultSubmitter) {
+		called = true
+	})
+	a, ok := r.Lookup("custom")
+	assert.True(t, ok)
+	a(PoolProfile{}, nil, 0, nil)
+	assert.True(t, called)
+}
+
+func Test_MwmMatch(t *testing.T) {
+	tickets := make(map[string]*Ticket)
+	for i, mmr := range []int64{1000, 2000, 1010, 2020} {
+		ti := soloTicket(string(rune('a'+i)), string(rune('a'+i))+"_1")
+		ti.IntArgs = []IntArg{{"mmr", mmr}}
+		ti.startMatch = int64(i)
+		tickets[ti.TicketId] = ti
+	}
+	pool := PoolProfile{
+		Name:             "1v1",
+		Teams:            []string{"red", "blue"},
+		TeamMembers:      1,
+		MaxMatchPerRound: 10,
+		QualityArg:       "mmr",
+	}
+	pairs := func(algorithm MatchAlgorithm) (r [][]string) {
+		for _, ti := range tickets {
+			ti.used = false
+		}
+		algorithm(pool, tickets, 0, func(mr MatchResult) {
+			r = append(r, []string{mr.Teams[0].TicketId[0], mr.Teams[1].TicketId[0]})
+		})
+		return r
+	}
+	assert.ElementsMatch(t, [][]string{{"a", "b"}, {"c", "d"}}, pairs(FifoMatch))
+	assert.ElementsMatch(t, [][]string{{"a", "c"}, {"b", "d"}}, pairs(MwmMatch))
+}
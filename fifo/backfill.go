@@ -0,0 +1,165 @@
+package fifo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrDuplicateBackfill = errors.New("fifo: duplicate backfill")
+	ErrBackfillNotFound  = errors.New("fifo: backfill not found")
+	ErrInvalidBackfill   = errors.New("fifo: invalid backfill")
+)
+
+// Backfill 运行中的对局，有队员离开后向匹配器登记空位，由排队中的 ticket 补充。
+// 补充时不考虑匹配池的 RoleQuotas，补充的队员 MemberResult.Role 为空。
+type Backfill struct {
+	BackfillId string         `json:"backfill_id"` // 全局唯一id，随补充结果 MatchResult.BackfillId 返回
+	PoolName   string         `json:"pool_name"`   // 从该匹配池中挑选 ticket，需要满足该池的过滤器
+	Teams      []BackfillTeam `json:"teams"`
+}
+
+// BackfillTeam 对局中的一队
+type BackfillTeam struct {
+	TeamName  string   `json:"team_name"`
+	Open      int      `json:"open"`       // 空位数
+	BlackIds  []int64  `json:"black_ids"`  // 现有队员的 BlackId，补充的 ticket 不能将其列入黑名单
+	BlackList []int64  `json:"black_list"` // 现有队员的黑名单，补充的 ticket 不能包含其中的 BlackId
+	AntiTags  []string `json:"anti_tags"`  // 现有队员的 BetweenTeamAntiAffinity 选择词，其它队补充的 ticket 不能与之相同
+}
+
+// AddBackfill 登记对局空位，之后每轮匹配优先用该匹配池的 ticket 补充，补满后自动移除
+func (m *Matchmaker) AddBackfill(b Backfill) error {
+	open := 0
+	for _, bt := range b.Teams {
+		if bt.Open < 0 {
+			return fmt.Errorf("%w: team %q open %d", ErrInvalidBackfill, bt.TeamName, bt.Open)
+		}
+		open += bt.Open
+	}
+	if b.BackfillId == "" || open == 0 {
+		return ErrInvalidBackfill
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pool(b.PoolName) == nil {
+		return fmt.Errorf("%w: %q", ErrUnknownPool, b.PoolName)
+	}
+	if slices.ContainsFunc(m.backfills, func(b2 *Backfill) bool { return b2.BackfillId == b.BackfillId }) {
+		return fmt.Errorf("%w: %s", ErrDuplicateBackfill, b.BackfillId)
+	}
+	b.Teams = slices.Clone(b.Teams)
+	m.backfills = append(m.backfills, &b)
+	return nil
+}
+
+// CancelBackfill 取消尚未补满的对局空位
+func (m *Matchmaker) CancelBackfill(backfillId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := slices.IndexFunc(m.backfills, func(b *Backfill) bool { return b.BackfillId == backfillId })
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrBackfillNotFound, backfillId)
+	}
+	m.backfills = slices.Delete(m.backfills, i, i+1)
+	return nil
+}
+
+// backfill 按登记顺序用 tickets 补充 pool 中对局的空位，补满的对局被移除
+func (m *Matchmaker) backfill(pool *PoolProfile, tickets map[string]*Ticket, now int64) (results []MatchResult) {
+	for _, b := range m.backfills {
+		if b.PoolName != pool.Name {
+			continue
+		}
+		if r, ok := b.fill(pool, tickets, now); ok {
+			results = append(results, r)
+		}
+	}
+	m.backfills = slices.DeleteFunc(m.backfills, func(b *Backfill) bool {
+		return b.PoolName == pool.Name && b.open() == 0
+	})
+	return results
+}
+
+func (b *Backfill) open() int {
+	n := 0
+	for _, bt := range b.Teams {
+		n += bt.Open
+	}
+	return n
+}
+
+// fill 为每队按 ticket 人数从多到少、等待时间从长到短补充空位，不缩减可选队员。
+// 补充的 ticket 满足匹配池的过滤器、黑名单（AllowHate 时）、两两约束与队间反亲和性。
+func (b *Backfill) fill(pool *PoolProfile, tickets map[string]*Ticket, now int64) (MatchResult, bool) {
+	p := *pool
+	p.RoleQuotas = nil
+	mr := MatchResult{PoolName: pool.Name, BackfillId: b.BackfillId}
+	for i := range b.Teams {
+		bt := &b.Teams[i]
+		if bt.Open == 0 {
+			continue
+		}
+		c := newCandidate(&p, now)
+		c.size, c.minSize = bt.Open, 0
+		c.me, c.hates = slices.Clone(bt.BlackIds), slices.Clone(bt.BlackList)
+		queue := sortTicket(tickets, bt.Open)
+		for n := bt.Open; n >= 1; n-- {
+			for _, t := range queue[n] {
+				if t.used || c.high+n > c.size || c.allow(t) != "" || b.antiConflict(&p, i, t) || b.hateConflict(&p, i, t) {
+					continue
+				}
+				c.join(t)
+				if tag, ok := findString(t.StringArgs, p.BetweenTeamAntiAffinity); ok && p.BetweenTeamAntiAffinity != "" {
+					bt.AntiTags = appendUnique(bt.AntiTags, tag)
+				}
+			}
+		}
+		if len(c.tickets) == 0 {
+			continue
+		}
+		mr.Teams = append(mr.Teams, c.result(bt.TeamName))
+		bt.Open -= c.high
+		bt.BlackIds, bt.BlackList = c.me, c.hates
+	}
+	return mr, len(mr.Teams) > 0
+}
+
+// hateConflict 开启 HateBetweenTeams 时 t 是否与第 i 队以外的队员互相拉黑
+func (b *Backfill) hateConflict(pool *PoolProfile, i int, t *Ticket) bool {
+	if !pool.HateBetweenTeams {
+		return false
+	}
+	for j, bt := range b.Teams {
+		if j == i {
+			continue
+		}
+		if intersects(t.BlackList, bt.BlackIds) {
+			return true
+		}
+		for _, m := range t.Members {
+			if slices.Contains(bt.BlackList, m.BlackId) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// antiConflict t 的队间反亲和性选择词是否与第 i 队以外的队伍冲突
+func (b *Backfill) antiConflict(pool *PoolProfile, i int, t *Ticket) bool {
+	if pool.BetweenTeamAntiAffinity == "" {
+		return false
+	}
+	tag, ok := findString(t.StringArgs, pool.BetweenTeamAntiAffinity)
+	if !ok {
+		return false
+	}
+	for j, bt := range b.Teams {
+		if j != i && slices.Contains(bt.AntiTags, tag) {
+			return true
+		}
+	}
+	return false
+}
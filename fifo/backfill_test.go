@@ -0,0 +1,72 @@
+package fifo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Backfill(t *testing.T) {
+	clock := newManualClock()
+	var results []MatchResult
+	mm, err := NewMatchmaker(MatchProfile{
+		Tick: "1s",
+		Pools: []PoolProfile{{
+			Name:                    "2v2",
+			BetweenTeamAntiAffinity: "clan",
+			Teams:                   []string{"red", "blue"},
+			TeamMembers:             2,
+			MaxMatchPerRound:        10,
+			AllowHate:               true,
+		}},
+	}, func(r MatchResult) {
+		results = append(results, r)
+	}, WithClock(clock))
+	assert.NoError(t, err)
+
+	hater := soloTicket("1", "1_1")
+	hater.BlackList = []int64{100}
+	clan := soloTicket("2", "2_1")
+	clan.StringArgs = []StringArg{{"clan", "a"}}
+	for _, ti := range []*Ticket{hater, clan, soloTicket("3", "3_1")} {
+		assert.NoError(t, mm.Enqueue("2v2", ti))
+		clock.Advance(time.Millisecond)
+	}
+	b := Backfill{
+		BackfillId: "game1",
+		PoolName:   "2v2",
+		Teams: []BackfillTeam{
+			{TeamName: "red", Open: 1, BlackIds: []int64{100}},
+			{TeamName: "blue", Open: 1, AntiTags: []string{"a"}},
+		},
+	}
+	assert.NoError(t, mm.AddBackfill(b))
+	assert.ErrorIs(t, mm.AddBackfill(b), ErrDuplicateBackfill)
+	b.PoolName = "3v3"
+	assert.ErrorIs(t, mm.AddBackfill(b), ErrUnknownPool)
+
+	mm.Tick()
+	// 1 拉黑了 red 的队员，2 与 blue 的选择词相同不能进入 red，red 由 3 补充，blue 由等待更久的 1 补充
+	assert.Len(t, results, 1)
+	assert.Equal(t, "game1", results[0].BackfillId)
+	assert.Equal(t, "red", results[0].Teams[0].TeamName)
+	assert.Equal(t, []string{"3"}, results[0].Teams[0].TicketId)
+	assert.Equal(t, "blue", results[0].Teams[1].TeamName)
+	assert.Equal(t, []string{"1"}, results[0].Teams[1].TicketId)
+	info, _ := mm.Get("2")
+	assert.Equal(t, StatusQueued, info.Status)
+	assert.Empty(t, mm.backfills)
+	assert.ErrorIs(t, mm.CancelBackfill("game1"), ErrBackfillNotFound)
+}
+
+func Test_BackfillWithoutHate(t *testing.T) {
+	pool := PoolProfile{Name: "2v2", Teams: []string{"red", "blue"}, TeamMembers: 2, MaxMatchPerRound: 10}
+	hater := soloTicket("1", "1_1")
+	hater.BlackList = []int64{100}
+	b := &Backfill{BackfillId: "game1", PoolName: "2v2", Teams: []BackfillTeam{{TeamName: "red", Open: 1, BlackIds: []int64{100}}}}
+	// 匹配池未开启 AllowHate，补充时同样不检查黑名单
+	r, ok := b.fill(&pool, map[string]*Ticket{"1": hater}, 0)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"1"}, r.Teams[0].TicketId)
+}
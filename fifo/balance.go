@@ -0,0 +1,121 @@
+package fifo
+
+import "math"
+
+const (
+	BalanceAvg = "avg" // 比较各队按人数加权的平均值
+	BalanceSum = "sum" // 比较各队所有队员的总和
+)
+
+// balancePasses 平衡时最多进行的交换轮数
+const balancePasses = 16
+
+// Balance 多队匹配的评分平衡：在一场匹配选出的队伍之间交换 ticket，使各队 Arg 的差距最小。
+// 交换后的队伍仍需满足人数、黑名单、两两约束和队间反亲和性。
+type Balance struct {
+	Arg  string `json:"arg"`  // 参与平衡的 IntArg/FloatArg，ticket 的值视为其每个队员的值
+	Mode string `json:"mode"` // BalanceAvg 或 BalanceSum，为空时使用 BalanceAvg
+}
+
+func (b *Balance) value(c *candidate) float64 {
+	v := c.rating(b.Arg)
+	if b.Mode == BalanceSum {
+		n := 0
+		for _, t := range c.tickets {
+			n += len(t.Members)
+		}
+		v *= float64(n)
+	}
+	return v
+}
+
+func (b *Balance) imbalance(teams []*candidate) float64 {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, c := range teams {
+		v := b.value(c)
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	return hi - lo
+}
+
+// apply 贪心地交换两队之间的 ticket，每轮选择使差距下降最多的交换，b 为 nil 时原样返回
+func (b *Balance) apply(teams []*candidate) []*candidate {
+	if b == nil || len(teams) < 2 {
+		return teams
+	}
+	best := b.imbalance(teams)
+	for range balancePasses {
+		var next []*candidate
+		for x := 0; x < len(teams); x++ {
+			for y := x + 1; y < len(teams); y++ {
+				for i := range teams[x].tickets {
+					for j := range teams[y].tickets {
+						cx, cy, ok := swapTickets(teams[x], teams[y], i, j)
+						if !ok {
+							continue
+						}
+						trial := append([]*candidate(nil), teams...)
+						trial[x], trial[y] = cx, cy
+						if !validTeams(trial) {
+							continue
+						}
+						if v := b.imbalance(trial); v < best {
+							best, next = v, trial
+						}
+					}
+				}
+			}
+		}
+		if next == nil {
+			break
+		}
+		teams = next
+	}
+	return teams
+}
+
+// swapTickets 交换 a 的第 i 个 ticket 与 b 的第 j 个 ticket，返回重建后的两队
+func swapTickets(a, b *candidate, i, j int) (*candidate, *candidate, bool) {
+	ta := append([]*Ticket(nil), a.tickets...)
+	tb := append([]*Ticket(nil), b.tickets...)
+	ta[i], tb[j] = tb[j], ta[i]
+	ca, ok := rebuildCandidate(a, ta)
+	if !ok {
+		return nil, nil, false
+	}
+	cb, ok := rebuildCandidate(b, tb)
+	if !ok {
+		return nil, nil, false
+	}
+	return ca, cb, true
+}
+
+// rebuildCandidate 用给定 ticket 在 old 的位置上重新组队，检查队内约束和人数
+func rebuildCandidate(old *candidate, tickets []*Ticket) (*candidate, bool) {
+	c := newCandidate(old.pool, old.now)
+	c.size, c.minSize, c.slot = old.size, old.minSize, old.slot
+	for _, t := range tickets {
+		if len(c.tickets) > 0 && c.allow(t) != "" {
+			return nil, false
+		}
+		if len(c.tickets) == 0 && c.slot != nil && !c.slot.Allow(c.now, t) {
+			return nil, false
+		}
+		c.join(t)
+	}
+	if !c.complete(c.size) {
+		return nil, false
+	}
+	c.tagAnti()
+	c.used = true
+	return c, true
+}
+
+func validTeams(teams []*candidate) bool {
+	for i := 1; i < len(teams); i++ {
+		if !teams[i].matchTeam(teams[:i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package fifo
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Balance(t *testing.T) {
+	tickets := make(map[string]*Ticket)
+	for i, mmr := range []int64{1000, 1100, 2000, 2100} {
+		ti := soloTicket(string(rune('a'+i)), string(rune('a'+i)))
+		ti.IntArgs = []IntArg{{"mmr", mmr}}
+		ti.startMatch = int64(i)
+		tickets[ti.TicketId] = ti
+	}
+	pool := PoolProfile{
+		Teams:            []string{"red", "blue"},
+		TeamMembers:      2,
+		MaxMatchPerRound: 10,
+	}
+	results, _ := Match(pool, tickets, 0)
+	assert.Len(t, results, 1)
+	assert.Equal(t, []string{"a", "b"}, results[0].Teams[0].TicketId)
+	assert.Zero(t, results[0].Imbalance)
+
+	pool.Balance = &Balance{Arg: "mmr", Mode: BalanceSum}
+	results, _ = Match(pool, tickets, 0)
+	assert.Len(t, results, 1)
+	assert.ElementsMatch(t, [][]string{{"a", "d"}, {"b", "c"}}, teamsOf(results[0]))
+	assert.Zero(t, results[0].Imbalance)
+
+	// 黑名单不允许 a、d 同队时只能选择次优的分配
+	pool.AllowHate = true
+	tickets["a"].Members[0].BlackId = 1
+	tickets["d"].BlackList = []int64{1}
+	results, _ = Match(pool, tickets, 0)
+	assert.Len(t, results, 1)
+	assert.ElementsMatch(t, [][]string{{"a", "c"}, {"b", "d"}}, teamsOf(results[0]))
+	assert.Equal(t, float64(200), results[0].Imbalance)
+}
+
+func teamsOf(r MatchResult) (teams [][]string) {
+	for _, tr := range r.Teams {
+		teams = append(teams, slices.Sorted(slices.Values(tr.TicketId)))
+	}
+	return teams
+}
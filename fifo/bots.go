@@ -0,0 +1,63 @@
+package fifo
+
+// BotRequest 需要机器人补齐的队伍
+type BotRequest struct {
+	PoolName string
+	TeamName string
+	Tickets  []*Ticket // 队伍中的真人 ticket，可以根据其参数生成机器人
+	Count    int       // 需要的机器人数量
+	Roles    []string  // 开启 RoleQuotas 时每个机器人需要担任的角色，"" 表示不限
+}
+
+// BotProvider 为等待超过 PoolProfile.BotAfter 仍无法满员的队伍生成机器人，
+// 返回的队员会被标记为 Bot，少于 Count 时队伍保持未满员。
+// 在 Matchmaker 中 Bots 在一轮匹配内持有内部锁时调用，不能在其中调用 Matchmaker 的方法，否则会死锁。
+type BotProvider interface {
+	Bots(req BotRequest) []MemberResult
+}
+
+// BotProviderFunc 将函数适配为 BotProvider
+type BotProviderFunc func(req BotRequest) []MemberResult
+
+func (f BotProviderFunc) Bots(req BotRequest) []MemberResult {
+	return f(req)
+}
+
+// fillWithBots 队伍组不满时，若队首等待超过 BotAfter 则改为用机器人补齐，buf 中的 ticket 重新标记为 used
+func (p *PoolProfile) fillWithBots(buf *candidate) bool {
+	if !p.botReady(buf.now, buf.tickets[0]) || buf.low > buf.size {
+		return false
+	}
+	for _, t := range buf.tickets {
+		t.used = true
+	}
+	buf.bots = true
+	return true
+}
+
+// botReady 以 t 为队首的队伍是否可以用机器人补齐
+func (p *PoolProfile) botReady(now int64, t *Ticket) bool {
+	return p.Bots != nil && p.BotAfter > 0 && now-t.startMatch >= p.BotAfter
+}
+
+// fillBots 用机器人补齐 tr 的空位，roles 为空位对应的角色
+func (c *candidate) fillBots(tr *TeamResult, roles []string) {
+	n := c.size - len(tr.Members)
+	if n <= 0 {
+		return
+	}
+	bots := c.pool.Bots.Bots(BotRequest{
+		PoolName: c.pool.Name,
+		TeamName: tr.TeamName,
+		Tickets:  c.tickets,
+		Count:    n,
+		Roles:    roles,
+	})
+	for i, b := range bots[:min(n, len(bots))] {
+		b.Bot = true
+		if b.Role == "" && i < len(roles) {
+			b.Role = roles[i]
+		}
+		tr.Members = append(tr.Members, b)
+	}
+}
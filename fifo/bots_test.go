@@ -0,0 +1,50 @@
+package fifo
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FifoMatchBots(t *testing.T) {
+	var reqs []BotRequest
+	bots := BotProviderFunc(func(req BotRequest) []MemberResult {
+		reqs = append(reqs, req)
+		var ms []MemberResult
+		for i := range req.Count {
+			ms = append(ms, MemberResult{MemberId: "bot" + strconv.Itoa(i)})
+		}
+		return ms
+	})
+	ti := soloTicket("1", "1_1", "1_2")
+	ti.IntArgs = []IntArg{{"mmr", 1500}}
+	tickets := map[string]*Ticket{"1": ti}
+	pool := PoolProfile{
+		Name:             "coop",
+		Teams:            []string{"a"},
+		TeamMembers:      4,
+		MaxMatchPerRound: 10,
+		BotAfter:         5000,
+		Bots:             bots,
+		RoleQuotas:       []RoleQuota{{"healer", 1}},
+	}
+	results, _ := Match(pool, tickets, 4999)
+	assert.Empty(t, results)
+
+	results, remaining := Match(pool, tickets, 5000)
+	assert.Empty(t, remaining)
+	assert.Len(t, results, 1)
+	tr := results[0].Teams[0]
+	assert.Equal(t, []string{"1"}, tr.TicketId)
+	assert.Len(t, tr.Members, 4)
+	assert.Equal(t, 0, tr.Unfilled)
+	assert.False(t, tr.Members[0].Bot)
+	assert.True(t, tr.Members[3].Bot)
+	assert.Equal(t, "healer", tr.Members[2].Role)
+
+	assert.Len(t, reqs, 1)
+	assert.Equal(t, 2, reqs[0].Count)
+	assert.Equal(t, []string{"healer", ""}, reqs[0].Roles)
+	assert.Equal(t, []IntArg{{"mmr", 1500}}, reqs[0].Tickets[0].IntArgs)
+}
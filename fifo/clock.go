@@ -0,0 +1,36 @@
+package fifo
+
+import "time"
+
+// Clock 匹配器使用的时钟，测试时可替换为手动推进的实现
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+type systemTicker struct {
+	t *time.Ticker
+}
+
+func (s systemTicker) C() <-chan time.Time {
+	return s.t.C
+}
+
+func (s systemTicker) Stop() {
+	s.t.Stop()
+}
@@ -0,0 +1,111 @@
+package fifo
+
+import "strings"
+
+// Reason 诊断模式下 ticket 在一轮匹配中的结果
+type Reason string
+
+const (
+	ReasonMatched        Reason = "matched"         // 匹配成功
+	ReasonFiltered       Reason = "filtered"        // PoolProfile.Allow 或 TeamSlot 的过滤器拒绝，Detail 为拒绝的过滤器
+	ReasonTeamSize       Reason = "team_size"       // ticket 人数超过队伍人数
+	ReasonQuickFail      Reason = "quick_fail"      // 池内总人数不足以组成一场匹配
+	ReasonRoundLimit     Reason = "round_limit"     // 本轮已达到 MaxMatchPerRound，未轮到该 ticket
+	ReasonBlacklist      Reason = "blacklist"       // 组队时与其它 ticket 黑名单冲突
+	ReasonPairConstraint Reason = "pair_constraint" // 组队时与其它 ticket 不满足两两约束，Detail 为约束
+	ReasonRoles          Reason = "roles"           // 无法满足 RoleQuotas 的角色要求
+	ReasonNotEnough      Reason = "not_enough"      // 没有足够的 ticket 组成满员队伍
+	ReasonAntiAffinity   Reason = "anti_affinity"   // 队伍已组成，但因队间反亲和性找不到对手，Detail 为冲突的选择词
+	ReasonNoOpponent     Reason = "no_opponent"     // 队伍已组成，但没有足够的其它队伍
+)
+
+// conflictReason 将 search 返回的冲突约束转换为诊断原因
+func conflictReason(conflict string) Reason {
+	switch {
+	case conflict == "":
+		return ReasonNotEnough
+	case conflict == hateConflict:
+		return ReasonBlacklist
+	case conflict == roleConflict:
+		return ReasonRoles
+	case strings.HasPrefix(conflict, slotConflict):
+		return ReasonFiltered
+	}
+	return ReasonPairConstraint
+}
+
+// Diagnosis ticket 在某个匹配池一轮匹配中的诊断结果
+type Diagnosis struct {
+	TicketId string `json:"ticket_id"`
+	PoolName string `json:"pool_name"`
+	Tick     int64  `json:"tick"` // 该轮匹配的时间，epoch 单位ms
+	Reason   Reason `json:"reason"`
+	Detail   string `json:"detail"` // 造成该结果的过滤器或约束
+}
+
+// diagnostics 记录一轮匹配中每个 ticket 的诊断，nil 时所有记录操作为空操作
+type diagnostics struct {
+	pool string
+	now  int64
+	m    map[string]Diagnosis
+}
+
+func newDiagnostics(pool string, now int64) *diagnostics {
+	return &diagnostics{pool: pool, now: now, m: make(map[string]Diagnosis)}
+}
+
+func (d *diagnostics) record(t *Ticket, reason Reason, detail string) {
+	if d == nil {
+		return
+	}
+	d.m[t.TicketId] = Diagnosis{
+		TicketId: t.TicketId,
+		PoolName: d.pool,
+		Tick:     d.now,
+		Reason:   reason,
+		Detail:   detail,
+	}
+}
+
+// recordIfAbsent 只在 ticket 本轮还没有诊断时记录
+func (d *diagnostics) recordIfAbsent(t *Ticket, reason Reason, detail string) {
+	if d == nil {
+		return
+	}
+	if _, ok := d.m[t.TicketId]; !ok {
+		d.record(t, reason, detail)
+	}
+}
+
+func (d *diagnostics) recordCandidate(c *candidate, reason Reason, detail string) {
+	if d == nil {
+		return
+	}
+	for _, t := range c.tickets {
+		d.record(t, reason, detail)
+	}
+}
+
+// Explain 与 Match 相同，额外返回本轮每个 ticket 的诊断结果，顺序与 remaining 一致，匹配成功的排在最后
+func Explain(pool PoolProfile, tickets map[string]*Ticket, now int64) (results []MatchResult, remaining []*Ticket, diags []Diagnosis) {
+	pool.diag = newDiagnostics(pool.Name, now)
+	results, remaining = runAlgorithm(FifoMatch, pool, tickets, now)
+	return results, remaining, pool.diag.list(results, remaining)
+}
+
+// list 补全未记录的 ticket 并按 remaining、results 的顺序输出
+func (d *diagnostics) list(results []MatchResult, remaining []*Ticket) []Diagnosis {
+	r := make([]Diagnosis, 0, len(d.m))
+	for _, t := range remaining {
+		d.recordIfAbsent(t, ReasonNotEnough, "")
+		r = append(r, d.m[t.TicketId])
+	}
+	for _, mr := range results {
+		for _, tr := range mr.Teams {
+			for _, id := range tr.TicketId {
+				r = append(r, Diagnosis{TicketId: id, PoolName: d.pool, Tick: d.now, Reason: ReasonMatched})
+			}
+		}
+	}
+	return r
+}
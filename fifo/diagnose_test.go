@@ -0,0 +1,84 @@
+package fifo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func reasons(diags []Diagnosis) map[string]Reason {
+	r := make(map[string]Reason)
+	for _, d := range diags {
+		r[d.TicketId] = d.Reason
+	}
+	return r
+}
+
+func Test_Explain(t *testing.T) {
+	pool := PoolProfile{
+		Name:             "duo",
+		Teams:            []string{"a"},
+		TeamMembers:      2,
+		MaxMatchPerRound: 10,
+		AllowHate:        true,
+	}
+	tickets := map[string]*Ticket{
+		"1": soloTicket("1", "1_1"),
+		"2": soloTicket("2", "2_1", "2_2", "2_3"),
+	}
+	_, _, diags := Explain(pool, tickets, 0)
+	assert.Equal(t, map[string]Reason{"1": ReasonQuickFail, "2": ReasonTeamSize}, reasons(diags))
+
+	a := &Ticket{TicketId: "a", Members: []Member{{MemberId: "a", BlackId: 1}}, BlackList: []int64{2}}
+	b := &Ticket{TicketId: "b", Members: []Member{{MemberId: "b", BlackId: 2}}, startMatch: 1}
+	c := &Ticket{TicketId: "c", Members: []Member{{MemberId: "c", BlackId: 3}, {MemberId: "d", BlackId: 4}}}
+	results, remaining, diags := Explain(pool, map[string]*Ticket{"a": a, "b": b, "c": c}, 0)
+	assert.Len(t, results, 1)
+	assert.Equal(t, []*Ticket{a, b}, remaining)
+	assert.Equal(t, map[string]Reason{"a": ReasonBlacklist, "b": ReasonNotEnough, "c": ReasonMatched}, reasons(diags))
+
+	pool.Teams = []string{"red", "blue"}
+	pool.TeamMembers = 1
+	pool.BetweenTeamAntiAffinity = "guild"
+	x := soloTicket("x", "x_1")
+	x.StringArgs = []StringArg{{"guild", "g"}}
+	y := soloTicket("y", "y_1")
+	y.StringArgs = []StringArg{{"guild", "g"}}
+	_, _, diags = Explain(pool, map[string]*Ticket{"x": x, "y": y}, 0)
+	assert.Equal(t, map[string]Reason{"x": ReasonAntiAffinity, "y": ReasonAntiAffinity}, reasons(diags))
+	assert.Equal(t, "g", diags[0].Detail)
+}
+
+func Test_MatchmakerDiagnose(t *testing.T) {
+	clock := newManualClock()
+	mm, err := NewMatchmaker(MatchProfile{
+		Tick: "1s",
+		Pools: []PoolProfile{{
+			Name:             "eu",
+			StringFilters:    []StringFilter{{Arg: "region", Op: EqualOp, Value: "eu"}},
+			Teams:            []string{"a"},
+			TeamMembers:      2,
+			MaxMatchPerRound: 10,
+		}, {
+			Name:             "any",
+			Teams:            []string{"a"},
+			TeamMembers:      2,
+			MaxMatchPerRound: 10,
+		}},
+	}, func(MatchResult) {}, WithClock(clock), WithDiagnostics())
+	assert.NoError(t, err)
+	assert.NoError(t, mm.Enqueue("", soloTicket("1", "1_1")))
+	assert.NoError(t, mm.Enqueue("eu", soloTicket("2", "2_1")))
+	clock.Advance(time.Second)
+	mm.Tick()
+
+	now := clock.Now().UnixMilli()
+	assert.Equal(t, []Diagnosis{
+		{TicketId: "1", PoolName: "eu", Tick: now, Reason: ReasonFiltered, Detail: "string_filters[0]"},
+		{TicketId: "1", PoolName: "any", Tick: now, Reason: ReasonQuickFail},
+	}, mm.Diagnose("1"))
+	assert.Equal(t, []Diagnosis{
+		{TicketId: "2", PoolName: "eu", Tick: now, Reason: ReasonFiltered, Detail: "string_filters[0]"},
+	}, mm.Diagnose("2"))
+}
@@ -0,0 +1,117 @@
+package fifo
+
+import "fmt"
+
+// FilterExpr 过滤器表达式，每个节点只设置一个字段：AllOf、AnyOf 与 Not 组合子表达式，
+// String、Int、Float 为与 StringFilters 等相同的过滤器。如 platform=pc 或 crossplay=on：
+//
+//	{"any_of": [{"string": {"arg": "platform", "op": "=", "value": "pc"}},
+//	            {"string": {"arg": "crossplay", "op": "=", "value": "on"}}]}
+type FilterExpr struct {
+	AllOf  []FilterExpr  `json:"all_of"`
+	AnyOf  []FilterExpr  `json:"any_of"`
+	Not    *FilterExpr   `json:"not"`
+	String *StringFilter `json:"string"`
+	Int    *IntFilter    `json:"int"`
+	Float  *FloatFilter  `json:"float"`
+}
+
+// predicate 编译后的 FilterExpr
+type predicate func(now int64, t *Ticket) bool
+
+// validate 校验表达式，field 为字段路径，返回是否没有错误
+func (e *FilterExpr) validate(field string, fail func(field, format string, args ...any)) bool {
+	ok := true
+	failed := func(field, format string, args ...any) {
+		ok = false
+		fail(field, format, args...)
+	}
+	n := 0
+	for _, set := range []bool{e.AllOf != nil, e.AnyOf != nil, e.Not != nil, e.String != nil, e.Int != nil, e.Float != nil} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		failed(field, "must set exactly one of all_of, any_of, not, string, int, float")
+		return false
+	}
+	switch {
+	case e.AllOf != nil:
+		validateExprs(field+".all_of", e.AllOf, failed)
+	case e.AnyOf != nil:
+		validateExprs(field+".any_of", e.AnyOf, failed)
+	case e.Not != nil:
+		e.Not.validate(field+".not", failed)
+	case e.String != nil:
+		e.String.validate(field+".string", failed)
+	case e.Int != nil:
+		e.Int.validate(field+".int", failed)
+	case e.Float != nil:
+		e.Float.validate(field+".float", failed)
+	}
+	return ok
+}
+
+func validateExprs(field string, es []FilterExpr, fail func(field, format string, args ...any)) {
+	if len(es) == 0 {
+		fail(field, "must not be empty")
+	}
+	for i := range es {
+		es[i].validate(fmt.Sprintf("%s[%d]", field, i), fail)
+	}
+}
+
+// compile 将表达式编译为短路求值的 predicate，需要先通过 validate
+func (e *FilterExpr) compile() predicate {
+	switch {
+	case e.AllOf != nil:
+		ps := compileList(e.AllOf)
+		return func(now int64, t *Ticket) bool {
+			for _, p := range ps {
+				if !p(now, t) {
+					return false
+				}
+			}
+			return true
+		}
+	case e.AnyOf != nil:
+		ps := compileList(e.AnyOf)
+		return func(now int64, t *Ticket) bool {
+			for _, p := range ps {
+				if p(now, t) {
+					return true
+				}
+			}
+			return false
+		}
+	case e.Not != nil:
+		p := e.Not.compile()
+		return func(now int64, t *Ticket) bool {
+			return !p(now, t)
+		}
+	case e.String != nil:
+		f := *e.String
+		_ = f.compile()
+		return func(_ int64, t *Ticket) bool {
+			return f.allow(t)
+		}
+	case e.Int != nil:
+		f := *e.Int
+		f.compile()
+		return f.allow
+	case e.Float != nil:
+		f := *e.Float
+		f.compile()
+		return f.allow
+	}
+	return func(int64, *Ticket) bool { return true }
+}
+
+func compileList(es []FilterExpr) []predicate {
+	ps := make([]predicate, len(es))
+	for i := range es {
+		ps[i] = es[i].compile()
+	}
+	return ps
+}
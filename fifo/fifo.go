@@ -1,31 +1,84 @@
 package fifo
 
 import (
+	"fmt"
 	"slices"
 	"sort"
 )
 
 type candidate struct {
+	pool      *PoolProfile
+	now       int64
 	tickets   []*Ticket
 	me        []int64
 	hates     []int64
+	likes     []int64  // 队内 ticket 白名单的并集
+	tags      []string // 队内 ticket 的 AffinityArg 值
 	anti      []string
 	low, high int
+	size      int       // 队伍人数
+	minSize   int       // 队伍最少人数
+	slot      *TeamSlot // 使用 TeamSlots 时队伍所在的位置
+	bots      bool      // 空位由机器人补齐
 	used      bool
 }
 
+func newCandidate(pool *PoolProfile, now int64) *candidate {
+	return &candidate{pool: pool, now: now, size: pool.maxMembers(), minSize: pool.maxMembers()}
+}
+
 func (c *candidate) matchTeam(cs []*candidate) bool {
 	for _, c2 := range cs {
-		for _, id := range c.anti {
-			if slices.Index(c2.anti, id) >= 0 {
-				return false
-			}
+		if _, ok := c.conflict(c2); ok {
+			return false
+		}
+		if c.pool.HateBetweenTeams && c.hateTeam(c2) {
+			return false
 		}
 	}
 	return true
 }
 
-func (c *candidate) allow(t *Ticket) bool {
+// hateTeam 两队之间是否有队员将对方拉黑
+func (c *candidate) hateTeam(c2 *candidate) bool {
+	return intersects(c.me, c2.hates) || intersects(c2.me, c.hates)
+}
+
+// conflict 返回两队冲突的队间反亲和性选择词
+func (c *candidate) conflict(c2 *candidate) (string, bool) {
+	for _, id := range c.anti {
+		if slices.Index(c2.anti, id) >= 0 {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// allow 检查 t 能否加入队伍，返回冲突的约束，为空表示允许
+func (c *candidate) allow(t *Ticket) string {
+	if c.slot != nil {
+		if field, ok := c.slot.reject(c.now, t); !ok {
+			return c.slotField(field)
+		}
+	}
+	if c.pool.AllowHate && !c.allowHate(t) {
+		return hateConflict
+	}
+	if len(c.pool.RoleQuotas) > 0 && !c.rolesFit(t) {
+		return roleConflict
+	}
+	for i := range c.pool.PairConstraints {
+		pc := &c.pool.PairConstraints[i]
+		for _, t2 := range c.tickets {
+			if !pc.allow(c.now, t, t2) {
+				return fmt.Sprintf("pair_constraints[%d]", i)
+			}
+		}
+	}
+	return ""
+}
+
+func (c *candidate) allowHate(t *Ticket) bool {
 	for _, m := range t.Members {
 		if slices.Index(c.hates, m.BlackId) >= 0 {
 			return false
@@ -55,57 +108,156 @@ func (c *candidate) join(t *Ticket) {
 	for _, id := range t.BlackList {
 		c.hates = appendUnique(c.hates, id)
 	}
+	for _, id := range t.WhiteList {
+		c.likes = appendUnique(c.likes, id)
+	}
+	if c.pool.AffinityArg != "" {
+		if tag, ok := findString(t.StringArgs, c.pool.AffinityArg); ok {
+			c.tags = appendUnique(c.tags, tag)
+		}
+	}
 	c.tickets = append(c.tickets, t)
 }
 
-func (c *candidate) result(name string, n int) TeamResult {
+func (c *candidate) result(name string) TeamResult {
+	n := c.size
 	tr := TeamResult{
 		TeamName: name,
 		TicketId: make([]string, 0, len(c.tickets)),
 		Members:  make([]MemberResult, 0, n),
 	}
-	tt := 0
-	for _, t := range c.tickets {
-		tr.TicketId = append(tr.TicketId, t.TicketId)
-		tt += len(t.Members)
-		for _, m := range t.Members {
-			tr.Members = append(tr.Members, MemberResult{
-				MemberId: m.MemberId,
-				Extra:    m.Extra,
-				sort:     m.Sort,
+	var free []string
+	if len(c.pool.RoleQuotas) > 0 {
+		for _, t := range c.tickets {
+			tr.TicketId = append(tr.TicketId, t.TicketId)
+		}
+		free = c.roleResult(&tr)
+	} else {
+		for _, t := range c.tickets {
+			tr.TicketId = append(tr.TicketId, t.TicketId)
+			for _, m := range t.Members {
+				tr.Members = append(tr.Members, MemberResult{
+					MemberId: m.MemberId,
+					Extra:    m.Extra,
+					sort:     m.Sort,
+				})
+			}
+		}
+		if len(tr.Members) > n {
+			sort.Slice(tr.Members, func(i, j int) bool {
+				return tr.Members[i].sort < tr.Members[j].sort
 			})
+			tr.CutMembers = tr.Members[n:]
+			tr.Members = tr.Members[:n]
 		}
 	}
-	if tt <= n {
-		return tr
+	if c.bots {
+		c.fillBots(&tr, free)
 	}
-	sort.Slice(tr.Members, func(i, j int) bool {
-		return tr.Members[i].sort < tr.Members[j].sort
-	})
-	tr.CutMembers = tr.Members[n:]
-	tr.Members = tr.Members[:n]
+	tr.Unfilled = n - len(tr.Members)
 	return tr
 }
 
 func matchResult(pool PoolProfile, teams []*candidate) MatchResult {
-	n := min(len(pool.Teams), len(teams))
+	n := min(max(len(pool.Teams), len(pool.TeamSlots)), len(teams))
 	mr := MatchResult{
 		PoolName: pool.Name,
 		Teams:    make([]TeamResult, 0, n),
 	}
 	for i := 0; i < n; i++ {
-		mr.Teams = append(mr.Teams, teams[i].result(pool.Teams[i], pool.TeamMembers))
+		mr.Teams = append(mr.Teams, teams[i].result(teamName(pool, teams, i)))
 	}
+	if pool.Balance != nil {
+		mr.Imbalance = pool.Balance.imbalance(teams)
+	}
+	mr.Preferred = preferences(teams)
 	return mr
 }
 
 func FifoMatch(pool PoolProfile, tickets map[string]*Ticket, now int64, r ResultSubmitter) {
-	n := pool.TeamMembers
+	if len(pool.TeamSlots) > 0 {
+		slotMatch(pool, tickets, now, r)
+		return
+	}
+	cans := buildCandidates(pool, tickets, now)
 	m := len(pool.Teams)
-	queue := sortTicket(tickets, n)
-	if quickFail(queue, n, m) {
+	if len(cans) < m {
+		diagnoseTeams(pool.diag, cans)
+		return
+	}
+	// step 2. match between teams
+	if m == 1 {
+		for _, can := range cans {
+			r(MatchResult{
+				PoolName:  pool.Name,
+				Teams:     []TeamResult{can.result(pool.Teams[0])},
+				Preferred: preferences([]*candidate{can}),
+			})
+		}
 		return
 	}
+	for i := range cans {
+		if rr, ok := searchTeam(cans, i, m); ok {
+			r(matchResult(pool, pool.Balance.apply(rr)))
+		}
+	}
+	diagnoseTeams(pool.diag, cans)
+}
+
+// diagnoseTeams 记录已组成但没有进入匹配结果的队伍
+func diagnoseTeams(d *diagnostics, cans []*candidate) {
+	if d == nil {
+		return
+	}
+	for _, c := range cans {
+		if c.used {
+			continue
+		}
+		reason, detail := ReasonNoOpponent, ""
+		for _, c2 := range cans {
+			if c2 == c {
+				continue
+			}
+			if tag, ok := c.conflict(c2); ok {
+				reason, detail = ReasonAntiAffinity, tag
+				break
+			}
+			if c.pool.HateBetweenTeams && c.hateTeam(c2) {
+				reason, detail = ReasonBlacklist, "hate_between_teams"
+				break
+			}
+		}
+		d.recordCandidate(c, reason, detail)
+	}
+}
+
+// buildCandidates 第一步，将 ticket 组合为满员的队伍
+func buildCandidates(pool PoolProfile, tickets map[string]*Ticket, now int64) []*candidate {
+	n := pool.maxMembers()
+	m := len(pool.Teams)
+	if pool.diag != nil {
+		for _, t := range tickets {
+			if len(t.Members) > n {
+				pool.diag.record(t, ReasonTeamSize, "team_members")
+			}
+		}
+	}
+	queue := sortTicket(tickets, n)
+	least := n
+	for _, t := range tickets {
+		least = min(least, pool.minMembers(now, t))
+		if pool.botReady(now, t) {
+			least = 1
+		}
+	}
+	if quickFail(queue, n, least, m) {
+		for _, q := range queue {
+			for _, t := range q {
+				pool.diag.record(t, ReasonQuickFail, "")
+			}
+		}
+		return nil
+	}
 	var cans []*candidate
 	// step 1. match inside team
 MATCH:
@@ -114,44 +266,49 @@ MATCH:
 			if queue[i][j].used {
 				continue
 			}
-			buf := new(candidate)
+			buf := newCandidate(&pool, now)
+			buf.minSize = pool.minMembers(now, queue[i][j])
 			buf.join(queue[i][j])
-			ok := search(buf, queue, n, pool.AllowCut, pool.AllowHate)
+			ok, conflict := search(buf, queue, n, pool.AllowCut)
+			if !ok && pool.fillWithBots(buf) {
+				ok = true
+			}
 			if ok {
 				cans = append(cans, buf)
 				if len(cans) >= pool.MaxMatchPerRound {
 					break MATCH
 				}
+			} else {
+				pool.diag.recordCandidate(buf, conflictReason(conflict), conflict)
 			}
 		}
 		removeUsed(queue)
 	}
-	if len(cans) < m {
-		return
+	if pool.diag != nil && len(cans) >= pool.MaxMatchPerRound {
+		for _, q := range queue {
+			for _, t := range q {
+				if !t.used {
+					pool.diag.recordIfAbsent(t, ReasonRoundLimit, "max_match_per_round")
+				}
+			}
+		}
 	}
-	// step 2. match between teams
-	if m == 1 {
+	if m > 1 {
 		for _, can := range cans {
-			r(MatchResult{
-				PoolName: pool.Name,
-				Teams:    []TeamResult{can.result(pool.Teams[0], pool.TeamMembers)},
-			})
+			can.tagAnti()
 		}
-		return
 	}
+	return cans
+}
 
-	if pool.BetweenTeamAntiAffinity != "" {
-		for _, can := range cans {
-			for _, t := range can.tickets {
-				if tag, ok := findString(t.StringArgs, pool.BetweenTeamAntiAffinity); ok {
-					can.anti = appendUnique(can.anti, tag)
-				}
-			}
-		}
+// tagAnti 收集队伍的队间反亲和性选择词
+func (c *candidate) tagAnti() {
+	if c.pool.BetweenTeamAntiAffinity == "" {
+		return
 	}
-	for i := range cans {
-		if rr, ok := searchTeam(cans, i, m); ok {
-			r(matchResult(pool, rr))
+	for _, t := range c.tickets {
+		if tag, ok := findString(t.StringArgs, c.pool.BetweenTeamAntiAffinity); ok {
+			c.anti = appendUnique(c.anti, tag)
 		}
 	}
 }
@@ -161,12 +318,18 @@ func searchTeam(queue []*candidate, i, n int) (buf []*candidate, ok bool) {
 		return nil, false
 	}
 	buf = append(buf, queue[i])
-	for j := i + 1; j < len(queue); j++ {
-		if !queue[j].used && queue[j].matchTeam(buf) {
-			queue[j].used = true
-			buf = append(buf, queue[j])
-			if len(buf) == n {
-				return buf, true
+	// 第一遍只尝试与已选队伍亲和的队伍
+	for pass := 0; pass < 2; pass++ {
+		for j := i + 1; j < len(queue); j++ {
+			if queue[j].used || pass == 0 && !queue[j].prefersMatch(buf) {
+				continue
+			}
+			if queue[j].matchTeam(buf) {
+				queue[j].used = true
+				buf = append(buf, queue[j])
+				if len(buf) == n {
+					return buf, true
+				}
 			}
 		}
 	}
@@ -184,7 +347,14 @@ func removeUsed(queue [][]*Ticket) {
 	}
 }
 
-func search(buf *candidate, queue [][]*Ticket, need int, cut, hate bool) (ok bool) {
+// complete 队伍人数可以缩减到不超过 n 且不少于 minSize，且满足角色要求
+func (c *candidate) complete(n int) bool {
+	return c.low <= n && min(n, c.high) >= c.minSize && c.rolesComplete()
+}
+
+// search 为 buf 补齐队员，conflict 为搜索过程中第一个导致 ticket 被跳过的约束。
+// 因黑名单冲突失败时 buf 中的 ticket 保持 used，本轮不再参与匹配。
+func search(buf *candidate, queue [][]*Ticket, need int, cut bool) (ok bool, conflict string) {
 	var (
 		x        int
 		due2Hate bool
@@ -197,36 +367,72 @@ SEARCH:
 		} else {
 			x = min(need, need-buf.high)
 		}
-		for i := x; i >= 1; i-- {
-			for j := range queue[i] {
-				if !queue[i][j].used {
-					if !hate || buf.allow(queue[i][j]) {
-						buf.join(queue[i][j])
+		// 第一遍只尝试与队伍有偏好关系的 ticket
+		for pass := 0; pass < 2; pass++ {
+			for i := x; i >= 1; i-- {
+				for j := range queue[i] {
+					t := queue[i][j]
+					if t.used || pass == 0 && !buf.prefers(t) {
+						continue
+					}
+					reason := buf.allow(t)
+					if reason == "" {
+						buf.join(t)
 						continue SEARCH
 					}
-					due2Hate = true
+					if reason == hateConflict {
+						due2Hate = true
+					}
+					if conflict == "" {
+						conflict = reason
+					}
 				}
 			}
 		}
 		break
 	}
-	if buf.low <= need && need <= buf.high {
-		return true
+	if buf.complete(need) {
+		return true, conflict
+	}
+	if buf.low <= need && min(need, buf.high) >= buf.minSize && conflict == "" {
+		conflict = roleConflict
 	}
 	if !due2Hate {
 		for _, t := range buf.tickets {
 			t.used = false
 		}
+	} else {
+		conflict = hateConflict
 	}
-	return false
+	return false, conflict
 }
 
-func quickFail(queue [][]*Ticket, max, team int) bool {
+// quickFail 池内总人数按每队最少 least 人也组不出 team 队
+func quickFail(queue [][]*Ticket, max, least, team int) bool {
 	sum := 0
 	for i := 1; i <= max; i++ {
 		sum += len(queue[i]) * i
 	}
-	return sum/max < team
+	return sum/least < team
+}
+
+// maxMembers 每队最多人数，MaxTeamMembers 为 0 时使用 TeamMembers
+func (p *PoolProfile) maxMembers() int {
+	if p.MaxTeamMembers > 0 {
+		return p.MaxTeamMembers
+	}
+	return p.TeamMembers
+}
+
+// minMembers 以 t 为队首时队伍的最少人数
+func (p *PoolProfile) minMembers(now int64, t *Ticket) int {
+	if p.MinTeamMembers <= 0 {
+		return p.maxMembers()
+	}
+	if p.MinTeamRelax == nil {
+		return p.MinTeamMembers
+	}
+	return max(p.MinTeamMembers, p.maxMembers()-int(p.MinTeamRelax.widen(now, t)))
 }
 
 func sortTicket(ts map[string]*Ticket, max int) [][]*Ticket {
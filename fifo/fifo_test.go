@@ -68,16 +68,62 @@ var candidates = []*Ticket{
 func Test_quickFail(t *testing.T) {
 	q := make([][]*Ticket, 6)
 	q[5] = make([]*Ticket, 2)
-	assert.False(t, quickFail(q, 5, 2))
+	assert.False(t, quickFail(q, 5, 5, 2))
 
 	q[5] = nil
 	q[4] = make([]*Ticket, 1)
 	q[3] = make([]*Ticket, 1)
 	q[1] = make([]*Ticket, 2)
-	assert.True(t, quickFail(q, 5, 2))
+	assert.True(t, quickFail(q, 5, 5, 2))
 
 	q[1] = make([]*Ticket, 3)
-	assert.False(t, quickFail(q, 5, 2))
+	assert.False(t, quickFail(q, 5, 5, 2))
+
+	q[1] = nil
+	assert.True(t, quickFail(q, 5, 5, 2))
+	assert.False(t, quickFail(q, 5, 3, 2))
+}
+
+func Test_FifoMatchMinTeam(t *testing.T) {
+	tickets := make(map[string]*Ticket)
+	for _, id := range []string{"1", "2", "3", "4"} {
+		tickets[id] = soloTicket(id, id+"_1")
+	}
+	pool := PoolProfile{
+		Teams:            []string{"a"},
+		MaxTeamMembers:   5,
+		MinTeamMembers:   3,
+		MinTeamRelax:     &Relax{Every: 1000, Step: 1},
+		MaxMatchPerRound: 10,
+	}
+	results, _ := Match(pool, tickets, 999)
+	assert.Empty(t, results)
+	results, _ = Match(pool, tickets, 1000)
+	assert.Len(t, results, 1)
+	assert.Len(t, results[0].Teams[0].Members, 4)
+	assert.Equal(t, 1, results[0].Teams[0].Unfilled)
+
+	pool.MinTeamRelax = nil
+	for _, id := range []string{"5", "6", "7", "8"} {
+		tickets[id] = soloTicket(id, id+"_1")
+	}
+	// 先尽量满员，剩余 3 人仍满足最少人数
+	results, _ = Match(pool, tickets, 0)
+	assert.Len(t, results, 2)
+	assert.Equal(t, 0, results[0].Teams[0].Unfilled)
+	assert.Equal(t, 2, results[1].Teams[0].Unfilled)
+
+	p, err := ParseProfile([]byte(`{"pools": [{
+		"name": "casual", "teams": ["a"], "min_team_members": 6, "max_team_members": 5, "max_match_per_round": 1
+	}]}`), "json")
+	var es ValidationErrors
+	assert.ErrorAs(t, err, &es)
+	assert.Equal(t, "min_team_members", es[0].Field)
+	p.Pools[0].MinTeamMembers = 4
+	assert.NoError(t, p.Validate())
+	p.Pools[0].TeamMembers = 4
+	assert.ErrorAs(t, p.Validate(), &es)
+	assert.Equal(t, "max_team_members", es[0].Field)
 }
 
 func Test_sortTicket(t *testing.T) {
@@ -140,3 +186,29 @@ func Test_FifoMatch3(t *testing.T) {
 	})
 	fmt.Printf("%+v\n", results)
 }
+
+func Test_FifoMatchHateBetweenTeams(t *testing.T) {
+	tickets := make(map[string]*Ticket)
+	for i, id := range []string{"a", "b", "c"} {
+		ti := &Ticket{TicketId: id, Members: []Member{{MemberId: id, BlackId: int64(i + 1)}}, startMatch: int64(i)}
+		tickets[id] = ti
+	}
+	tickets["a"].BlackList = []int64{2}
+	pool := PoolProfile{
+		Teams:            []string{"1", "2"},
+		TeamMembers:      1,
+		MaxMatchPerRound: 10,
+		AllowHate:        true,
+	}
+	results, _ := Match(pool, tickets, 10)
+	assert.Equal(t, []string{"a"}, results[0].Teams[0].TicketId)
+	assert.Equal(t, []string{"b"}, results[0].Teams[1].TicketId)
+
+	pool.HateBetweenTeams = true
+	results, remaining, diags := Explain(pool, tickets, 10)
+	assert.Len(t, results, 1)
+	assert.Equal(t, []string{"a"}, results[0].Teams[0].TicketId)
+	assert.Equal(t, []string{"c"}, results[0].Teams[1].TicketId)
+	assert.Equal(t, []*Ticket{tickets["b"]}, remaining)
+	assert.Equal(t, Diagnosis{TicketId: "b", Tick: 10, Reason: ReasonBlacklist, Detail: "hate_between_teams"}, diags[0])
+}
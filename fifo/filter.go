@@ -1,24 +1,67 @@
 package fifo
 
-import "slices"
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+)
 
 func (p *PoolProfile) Allow(now int64, t *Ticket) bool {
-	for _, f := range p.StringFilters {
+	_, ok := p.reject(now, t)
+	return ok
+}
+
+// reject 返回拒绝 ticket 的过滤器，字段名与 Validate 一致
+func (p *PoolProfile) reject(now int64, t *Ticket) (string, bool) {
+	t = p.party(t)
+	if field, ok := rejectFilters(now, t, p.StringFilters, p.IntFilters, p.FloatFilters); !ok {
+		return field, false
+	}
+	if p.Filter != nil {
+		f := p.filter
+		if f == nil {
+			// 未经 Compile 的配置每次编译
+			f = p.Filter.compile()
+		}
+		if !f(now, t) {
+			return "filter", false
+		}
+	}
+	if p.Rule != "" {
+		f := p.rule
+		if f == nil {
+			// 未经 Compile 的配置每次编译，规则错误由 Validate 与 Compile 返回，此时拒绝所有 ticket
+			var err error
+			if f, err = compileRule(p.Rule); err != nil {
+				return "rule", false
+			}
+		}
+		if !f(now, t) {
+			return "rule", false
+		}
+	}
+	return "", true
+}
+
+func rejectFilters(now int64, t *Ticket, sf []StringFilter, inf []IntFilter, ff []FloatFilter) (string, bool) {
+	for i, f := range sf {
 		if !f.allow(t) {
-			return false
+			return fmt.Sprintf("string_filters[%d]", i), false
 		}
 	}
-	for _, f := range p.IntFilters {
+	for i, f := range inf {
 		if !f.allow(now, t) {
-			return false
+			return fmt.Sprintf("int_filters[%d]", i), false
 		}
 	}
-	for _, f := range p.FloatFilters {
-		if !f.allow(t) {
-			return false
+	for i, f := range ff {
+		if !f.allow(now, t) {
+			return fmt.Sprintf("float_filters[%d]", i), false
 		}
 	}
-	return true
+	return "", true
 }
 
 func findString(args []StringArg, key string) (string, bool) {
@@ -49,43 +92,156 @@ func findFloat(args []FloatArg, key string) (float64, bool) {
 }
 
 func (f *StringFilter) allow(t *Ticket) bool {
-	if f.Op == EqualOp {
-		s, ok := findString(t.StringArgs, f.Arg)
-		if !ok {
-			return false
-		}
-		return f.Value == s
+	if f.Members != "" {
+		return quantify(t, f.Members, func(m *Member) bool { return f.match(m.StringArgs) })
 	}
-	if f.Op == NotEqualOp {
-		s, ok := findString(t.StringArgs, f.Op)
-		if !ok {
-			return true
+	return f.match(t.StringArgs)
+}
+
+func (f *StringFilter) match(args []StringArg) bool {
+	s, ok := findString(args, f.Arg)
+	switch f.Op {
+	case ExistsOp:
+		return ok
+	case MissingOp:
+		return !ok
+	case NotEqualOp:
+		return !ok || f.Value != s
+	case NotInOp:
+		return !ok || !slices.Contains(f.Values, s)
+	}
+	if !ok {
+		return false
+	}
+	switch f.Op {
+	case EqualOp:
+		return f.Value == s
+	case InOp:
+		return slices.Contains(f.Values, s)
+	case PrefixOp:
+		return strings.HasPrefix(s, f.Value)
+	case SuffixOp:
+		return strings.HasSuffix(s, f.Value)
+	case RegexOp:
+		if f.re == nil {
+			// 未经 Compile 的配置每次编译
+			ok, _ = regexp.MatchString(f.Value, s)
+			return ok
 		}
-		return f.Value != s
+		return f.re.MatchString(s)
+	}
+	return false
+}
+
+// compile 编译 RegexOp 的正则表达式
+func (f *StringFilter) compile() error {
+	if f.Op != RegexOp {
+		return nil
+	}
+	re, err := regexp.Compile(f.Value)
+	if err != nil {
+		return err
+	}
+	f.re = re
+	return nil
+}
+
+// compile 解析伪参数，未注册的伪参数保持为 nil
+func (f *IntFilter) compile() {
+	if isPseudo(f.Arg) {
+		f.pseudo, _ = lookupPseudo(f.Arg)
+	}
+}
+
+func (f *FloatFilter) compile() {
+	if isPseudo(f.Arg) {
+		f.pseudo, _ = lookupPseudo(f.Arg)
 	}
-	return true
 }
 
 func (f *IntFilter) allow(now int64, t *Ticket) bool {
-	var i int64
-	switch f.Arg {
-	case "$wait":
-		i = now - t.startMatch
-	case "$member":
-		i = int64(len(t.Members))
-	default:
-		var ok bool
-		if i, ok = findInt(t.IntArgs, f.Arg); !ok {
-			return false
+	lo, hi := f.Min, f.Max
+	if w := f.Relax.widen(now, t); w > 0 {
+		lo, hi = subSat(lo, int64(w)), addSat(hi, int64(w))
+	}
+	in := func(i int64) bool {
+		return i >= lo && i <= hi && slices.Index(f.Excludes, i) < 0
+	}
+	match := func(args []IntArg) bool {
+		i, ok := findInt(args, f.Arg)
+		return ok && in(i)
+	}
+	switch {
+	case isPseudo(f.Arg):
+		g := f.pseudo
+		if g == nil {
+			// 未经 Compile 的过滤器每次查找，未注册的伪参数视为 ticket 没有该参数
+			var ok bool
+			if g, ok = lookupPseudo(f.Arg); !ok {
+				return false
+			}
 		}
+		v, ok := g(now, t)
+		return ok && in(int64(v))
+	case f.Members != "":
+		return quantify(t, f.Members, func(m *Member) bool { return match(m.IntArgs) })
 	}
-	return i >= f.Min && i <= f.Max && slices.Index(f.Excludes, i) < 0
+	return match(t.IntArgs)
 }
 
-func (f *FloatFilter) allow(t *Ticket) bool {
-	i, ok := findFloat(t.FloatArgs, f.Arg)
-	if !ok {
-		return false
+func (f *FloatFilter) allow(now int64, t *Ticket) bool {
+	w := f.Relax.widen(now, t)
+	in := func(i float64) bool {
+		return i >= f.Min-w && i <= f.Max+w
+	}
+	match := func(args []FloatArg) bool {
+		i, ok := findFloat(args, f.Arg)
+		return ok && in(i)
+	}
+	switch {
+	case isPseudo(f.Arg):
+		g := f.pseudo
+		if g == nil {
+			// 未经 Compile 的过滤器每次查找，未注册的伪参数视为 ticket 没有该参数
+			var ok bool
+			if g, ok = lookupPseudo(f.Arg); !ok {
+				return false
+			}
+		}
+		v, ok := g(now, t)
+		return ok && in(v)
+	case f.Members != "":
+		return quantify(t, f.Members, func(m *Member) bool { return match(m.FloatArgs) })
+	}
+	return match(t.FloatArgs)
+}
+
+// widen 返回 ticket 当前等待时间下的累计放宽量，r 为 nil 时不放宽
+func (r *Relax) widen(now int64, t *Ticket) float64 {
+	if r == nil || r.Every <= 0 {
+		return 0
+	}
+	wait := now - t.startMatch
+	if wait < r.Every {
+		return 0
+	}
+	w := float64(wait/r.Every) * r.Step
+	if r.Limit > 0 {
+		w = min(w, r.Limit)
+	}
+	return w
+}
+
+func addSat(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+func subSat(a, b int64) int64 {
+	if a < math.MinInt64+b {
+		return math.MinInt64
 	}
-	return i >= f.Min && i <= f.Max
+	return a - b
 }
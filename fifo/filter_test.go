@@ -0,0 +1,100 @@
+package fifo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RelaxFilter(t *testing.T) {
+	p, err := ParseProfile([]byte(`{"pools": [{
+		"name": "ranked",
+		"int_filters": [{"arg": "mmr", "min": 1400, "max": 1600, "relax": {"every": 10000, "step": 50, "limit": 400}}],
+		"float_filters": [{"arg": "kd", "min": 1, "max": 2, "relax": {"every": 5000, "step": 0.5}}],
+		"teams": ["a"], "team_members": 1, "max_match_per_round": 1
+	}]}`), "json")
+	assert.NoError(t, err)
+	pool := p.Pools[0]
+	ti := &Ticket{IntArgs: []IntArg{{"mmr", 1700}}, FloatArgs: []FloatArg{{"kd", 2.5}}}
+	assert.False(t, pool.Allow(0, ti))
+	assert.False(t, pool.Allow(19999, ti))
+	assert.True(t, pool.Allow(20000, ti))
+
+	ti.IntArgs[0].Value = 1000
+	assert.True(t, pool.Allow(1_000_000, ti))
+	ti.IntArgs[0].Value = 999
+	assert.False(t, pool.Allow(1_000_000, ti))
+
+	f := IntFilter{Arg: "$wait", Min: 0, Max: math.MaxInt64, Relax: &Relax{Every: 1, Step: 1}}
+	assert.True(t, f.allow(100, ti))
+	raw := PoolProfile{IntFilters: []IntFilter{f}, FloatFilters: []FloatFilter{{Arg: "$level", Max: 1}}}
+	field, _ := raw.reject(100, ti)
+	assert.Equal(t, "float_filters[0]", field)
+
+	p.Pools[0].IntFilters[0].Relax.Every = 0
+	var es ValidationErrors
+	assert.ErrorAs(t, p.Validate(), &es)
+	assert.Equal(t, "int_filters[0].relax", es[0].Field)
+}
+
+func Test_StringFilterOps(t *testing.T) {
+	p, err := ParseProfile([]byte(`{"pools": [{
+		"name": "ops",
+		"string_filters": [
+			{"arg": "mode", "op": "!=", "value": "duo"},
+			{"arg": "region", "op": "in", "values": ["eu", "na"]},
+			{"arg": "ban", "op": "not_in", "values": ["chat"]},
+			{"arg": "version", "op": "prefix", "value": "1.2"},
+			{"arg": "host", "op": "suffix", "value": ".eu"},
+			{"arg": "name", "op": "regex", "value": "^[a-z]+[0-9]*$"},
+			{"arg": "token", "op": "exists"},
+			{"arg": "guest", "op": "missing"}
+		],
+		"teams": ["a"], "team_members": 1, "max_match_per_round": 1
+	}]}`), "json")
+	assert.NoError(t, err)
+	pool := p.Pools[0]
+	assert.NotNil(t, pool.StringFilters[5].re)
+	args := []StringArg{
+		{"region", "eu"}, {"version", "1.2.3"}, {"host", "a.eu"}, {"name", "bob42"}, {"token", ""},
+	}
+	ti := &Ticket{StringArgs: args}
+	reject := func(arg StringArg) string {
+		ti.StringArgs = append(append([]StringArg(nil), arg), args...)
+		f, _ := pool.reject(0, ti)
+		return f
+	}
+	assert.True(t, pool.Allow(0, ti))
+	assert.Equal(t, "string_filters[0]", reject(StringArg{"mode", "duo"}))
+	assert.Equal(t, "", reject(StringArg{"mode", "solo"}))
+	assert.Equal(t, "string_filters[1]", reject(StringArg{"region", "asia"}))
+	assert.Equal(t, "string_filters[2]", reject(StringArg{"ban", "chat"}))
+	assert.Equal(t, "string_filters[3]", reject(StringArg{"version", "1.3"}))
+	assert.Equal(t, "string_filters[4]", reject(StringArg{"host", "a.na"}))
+	assert.Equal(t, "string_filters[5]", reject(StringArg{"name", "Bob"}))
+	assert.Equal(t, "string_filters[7]", reject(StringArg{"guest", "1"}))
+	ti.StringArgs = args[:4] // 没有 token
+	f, _ := pool.reject(0, ti)
+	assert.Equal(t, "string_filters[6]", f)
+
+	// 未经 Compile 的正则表达式同样生效
+	raw := pool
+	raw.StringFilters = append([]StringFilter(nil), pool.StringFilters...)
+	raw.StringFilters[5].re = nil
+	ti.StringArgs = append([]StringArg{{"name", "Bob"}}, args...)
+	f, _ = raw.reject(0, ti)
+	assert.Equal(t, "string_filters[5]", f)
+	assert.NotNil(t, pool.StringFilters[5].re)
+
+	_, err = ParseProfile([]byte(`{"pools": [{
+		"name": "bad",
+		"string_filters": [{"arg": "a", "op": "in"}, {"arg": "b", "op": "regex", "value": "("}, {"arg": "c", "op": "like"}],
+		"teams": ["a"], "team_members": 1, "max_match_per_round": 1
+	}]}`), "json")
+	var es ValidationErrors
+	assert.ErrorAs(t, err, &es)
+	assert.Equal(t, "string_filters[0].values", es[0].Field)
+	assert.Equal(t, "string_filters[1].value", es[1].Field)
+	assert.Equal(t, "string_filters[2].op", es[2].Field)
+}
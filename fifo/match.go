@@ -0,0 +1,47 @@
+package fifo
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Match 是 FifoMatch 的无副作用版本：在 ticket 的副本上匹配，不修改调用方持有的 ticket，
+// 返回匹配结果以及未被选中的 ticket（按开始匹配时间排序），可在不同 goroutine 中并发调用。
+func Match(pool PoolProfile, tickets map[string]*Ticket, now int64) (results []MatchResult, remaining []*Ticket) {
+	return runAlgorithm(FifoMatch, pool, tickets, now)
+}
+
+func runAlgorithm(algorithm MatchAlgorithm, pool PoolProfile, tickets map[string]*Ticket, now int64) (results []MatchResult, remaining []*Ticket) {
+	if !pool.compiled {
+		pool.compile()
+	}
+	copies := make(map[string]*Ticket, len(tickets))
+	buf := make([]Ticket, 0, len(tickets))
+	for id, t := range tickets {
+		buf = append(buf, *t)
+		c := &buf[len(buf)-1]
+		c.used = false
+		copies[id] = pool.party(c)
+	}
+	algorithm(pool, copies, now, func(r MatchResult) {
+		results = append(results, r)
+	})
+	for _, r := range results {
+		for _, tr := range r.Teams {
+			for _, id := range tr.TicketId {
+				delete(copies, id)
+			}
+		}
+	}
+	remaining = make([]*Ticket, 0, len(copies))
+	for id := range copies {
+		remaining = append(remaining, tickets[id])
+	}
+	slices.SortFunc(remaining, func(a, b *Ticket) int {
+		if c := cmp.Compare(a.startMatch, b.startMatch); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.TicketId, b.TicketId)
+	})
+	return results, remaining
+}
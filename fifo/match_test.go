@@ -0,0 +1,46 @@
+package fifo
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Match(t *testing.T) {
+	tickets := make(map[string]*Ticket)
+	for i, ti := range candidates {
+		c := *ti
+		c.startMatch = int64(i)
+		c.used = false
+		tickets[ti.TicketId] = &c
+	}
+	pool := PoolProfile{
+		Teams:            []string{"1"},
+		TeamMembers:      5,
+		MaxMatchPerRound: 2,
+	}
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, remaining := Match(pool, tickets, time.Now().UnixMilli())
+			assert.Len(t, results, 2)
+			assert.Len(t, remaining, 2)
+		}()
+	}
+	wg.Wait()
+	for _, ti := range tickets {
+		assert.False(t, ti.used)
+	}
+
+	results, remaining := Match(pool, tickets, time.Now().UnixMilli())
+	var matched []string
+	for _, r := range results {
+		matched = append(matched, r.Teams[0].TicketId...)
+	}
+	assert.ElementsMatch(t, []string{"5", "4", "1"}, matched)
+	assert.Equal(t, []*Ticket{tickets["2"], tickets["3"]}, remaining)
+}
@@ -0,0 +1,440 @@
+package fifo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownPool      = errors.New("fifo: unknown pool")
+	ErrUnknownAlgorithm = errors.New("fifo: unknown algorithm")
+	ErrInvalidTick      = errors.New("fifo: invalid tick")
+	ErrUnknownRouting   = errors.New("fifo: unknown routing")
+	ErrRunning          = errors.New("fifo: matchmaker already running")
+)
+
+// Matchmaker 按 MatchProfile.Tick 周期性地对每个 PoolProfile 执行匹配
+type Matchmaker struct {
+	profile   MatchProfile
+	tick      time.Duration
+	registry  AlgorithmRegistry
+	algorithm MatchAlgorithm
+	clock     Clock
+	retention time.Duration
+	submitter ResultSubmitter
+	expired   ExpiredSubmitter
+	diagnose  bool
+	bots      BotProvider
+
+	mu     sync.Mutex
+	store  *TicketStore
+	pools  []*poolState
+	routed map[string]*Ticket // 未指定匹配池，按 MatchProfile.Routing 路由的 ticket
+	diags  map[string][]Diagnosis
+	// 等待补充空位的运行中对局，按登记顺序
+	backfills []*Backfill
+	ready     *ReadyCheck
+	seq       uint64
+	proposals map[string]*proposal
+	cooldown  map[string]int64 // MemberId -> 冷却结束时间
+	running   bool             // 匹配循环是否在运行，由 Start/Stop 设置
+
+	run    sync.Mutex // 串行化 Start/Stop
+	stop   chan struct{}
+	done   chan struct{}
+	retick chan Ticker // Reload 修改 tick 时放入新的 Ticker，只保留最新的一个
+}
+
+type poolState struct {
+	profile PoolProfile
+	tickets map[string]*Ticket // 指定进入该匹配池的 ticket
+}
+
+type Option func(*Matchmaker)
+
+// WithClock 替换默认的系统时钟
+func WithClock(c Clock) Option {
+	return func(m *Matchmaker) {
+		m.clock = c
+	}
+}
+
+// WithRegistry 替换查找 MatchProfile.Algorithm 使用的注册表，默认为 DefaultRegistry
+func WithRegistry(r AlgorithmRegistry) Option {
+	return func(m *Matchmaker) {
+		m.registry = r
+	}
+}
+
+// WithRetention 设置已结束 ticket 仍可通过 Get 查询的时长，默认 1 分钟
+func WithRetention(d time.Duration) Option {
+	return func(m *Matchmaker) {
+		m.retention = d
+	}
+}
+
+// WithExpiredSubmitter 设置超时 ticket 的通知回调
+func WithExpiredSubmitter(e ExpiredSubmitter) Option {
+	return func(m *Matchmaker) {
+		m.expired = e
+	}
+}
+
+// WithDiagnostics 开启诊断模式，记录每个 ticket 每轮未匹配的原因，见 Matchmaker.Diagnose
+func WithDiagnostics() Option {
+	return func(m *Matchmaker) {
+		m.diagnose = true
+	}
+}
+
+// WithBotProvider 为没有设置 PoolProfile.Bots 的匹配池设置机器人来源
+func WithBotProvider(b BotProvider) Option {
+	return func(m *Matchmaker) {
+		m.bots = b
+	}
+}
+
+func NewMatchmaker(profile MatchProfile, r ResultSubmitter, opts ...Option) (*Matchmaker, error) {
+	m := &Matchmaker{
+		registry:  DefaultRegistry,
+		clock:     systemClock{},
+		retention: time.Minute,
+		submitter: r,
+		retick:    make(chan Ticker, 1),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	profile, tick, algorithm, err := m.check(profile)
+	if err != nil {
+		return nil, err
+	}
+	m.profile, m.tick, m.algorithm = profile, tick, algorithm
+	m.store = NewTicketStore(m.clock)
+	m.routed = make(map[string]*Ticket)
+	m.proposals = make(map[string]*proposal)
+	m.cooldown = make(map[string]int64)
+	for _, p := range profile.Pools {
+		m.pools = append(m.pools, &poolState{
+			profile: p,
+			tickets: make(map[string]*Ticket),
+		})
+	}
+	return m, nil
+}
+
+// check 校验并编译配置，解析出 tick 与匹配算法
+func (m *Matchmaker) check(profile MatchProfile) (MatchProfile, time.Duration, MatchAlgorithm, error) {
+	profile, err := profile.compile()
+	if err != nil {
+		return profile, 0, nil, err
+	}
+	tick, err := time.ParseDuration(profile.Tick)
+	if err != nil || tick <= 0 {
+		return profile, 0, nil, fmt.Errorf("%w: %q", ErrInvalidTick, profile.Tick)
+	}
+	algorithm, ok := m.registry.Lookup(profile.Algorithm)
+	if !ok {
+		return profile, 0, nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, profile.Algorithm)
+	}
+	switch profile.Routing {
+	case "", RouteFirst, RouteAll, RouteFallthrough:
+	default:
+		return profile, 0, nil, fmt.Errorf("%w: %q", ErrUnknownRouting, profile.Routing)
+	}
+	return profile, tick, algorithm, nil
+}
+
+// Enqueue 将 ticket 放入指定匹配池排队，pool 为空时按 MatchProfile.Routing 自动路由，见 TicketStore.Enqueue。
+// 指定匹配池的 ticket 每轮需要通过该池的 Allow 才参与匹配，未通过时留在池中，直到 Allow 或超时。
+func (m *Matchmaker) Enqueue(pool string, t *Ticket) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.cooling(t, m.clock.Now().UnixMilli()); err != nil {
+		return err
+	}
+	if pool == "" {
+		if err := m.store.Enqueue(t); err != nil {
+			return err
+		}
+		m.routed[t.TicketId] = t
+		return nil
+	}
+	p := m.pool(pool)
+	if p == nil {
+		return fmt.Errorf("%w: %q", ErrUnknownPool, pool)
+	}
+	if err := m.store.Enqueue(t); err != nil {
+		return err
+	}
+	t.endMatch = deadline(t, p.profile)
+	p.tickets[t.TicketId] = t
+	return nil
+}
+
+func (m *Matchmaker) Cancel(ticketId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.store.Cancel(ticketId); err != nil {
+		return err
+	}
+	m.remove(ticketId)
+	return nil
+}
+
+func (m *Matchmaker) Update(ticketId string, args TicketArgs) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.store.Update(ticketId, args)
+}
+
+func (m *Matchmaker) Get(ticketId string) (TicketInfo, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.store.Get(ticketId)
+}
+
+func (m *Matchmaker) pool(name string) *poolState {
+	for _, p := range m.pools {
+		if p.profile.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Start 启动后台匹配循环
+func (m *Matchmaker) Start() error {
+	m.run.Lock()
+	defer m.run.Unlock()
+	if m.stop != nil {
+		return ErrRunning
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	m.mu.Lock()
+	tick := m.tick
+	m.running = true
+	m.mu.Unlock()
+	go m.loop(m.clock.NewTicker(tick), m.stop, m.done)
+	return nil
+}
+
+// Stop 停止匹配循环，会等待正在执行的一轮匹配及其结果投递完成。
+// 回调由匹配循环调用，因此不能在 ResultSubmitter、ExpiredSubmitter 等回调中调用 Stop，否则会死锁。
+func (m *Matchmaker) Stop() {
+	m.run.Lock()
+	defer m.run.Unlock()
+	if m.stop == nil {
+		return
+	}
+	m.mu.Lock()
+	m.running = false
+	m.mu.Unlock()
+	close(m.stop)
+	<-m.done
+	// 匹配循环退出前未取走的 Ticker
+	select {
+	case t := <-m.retick:
+		t.Stop()
+	default:
+	}
+	m.stop, m.done = nil, nil
+}
+
+func (m *Matchmaker) loop(ticker Ticker, stop, done chan struct{}) {
+	defer close(done)
+	defer func() { ticker.Stop() }()
+	for {
+		select {
+		case <-stop:
+			return
+		case t := <-m.retick:
+			ticker.Stop()
+			ticker = t
+		case <-ticker.C():
+			select {
+			case t := <-m.retick:
+				// tick 已被 Reload 修改，丢弃旧 Ticker 的信号
+				ticker.Stop()
+				ticker = t
+				continue
+			default:
+			}
+			m.Tick()
+		}
+	}
+}
+
+// replaceTicker 替换匹配循环待接收的 Ticker，调用方持有 m.mu，因此不会阻塞，
+// 可以在 ResultSubmitter 等由匹配循环调用的回调中使用
+func (m *Matchmaker) replaceTicker(tick time.Duration) {
+	if !m.running {
+		return
+	}
+	select {
+	case t := <-m.retick:
+		t.Stop()
+	default:
+	}
+	m.retick <- m.clock.NewTicker(tick)
+}
+
+// Tick 立即执行一轮匹配，结果在释放内部锁之后投递
+func (m *Matchmaker) Tick() {
+	m.mu.Lock()
+	now := m.clock.Now().UnixMilli()
+	m.store.Sweep(now - m.retention.Milliseconds())
+	expired := m.expire(now)
+	var cancels []ProposalCancel
+	if m.ready != nil {
+		cancels = m.timeoutProposals(now)
+	}
+	var diags map[string][]Diagnosis
+	if m.diagnose {
+		diags = make(map[string][]Diagnosis)
+	}
+	sets := m.route(now, diags)
+	for _, set := range sets {
+		for id := range set {
+			m.store.setStatus(id, StatusMatching)
+		}
+	}
+	var results []MatchResult
+	claim := func(rs []MatchResult) {
+		status := StatusMatched
+		if m.ready != nil {
+			status = StatusProposed
+		}
+		for i, r := range rs {
+			if m.ready != nil {
+				m.propose(&rs[i], now)
+			}
+			for _, tr := range r.Teams {
+				for _, id := range tr.TicketId {
+					// 被本池选中的 ticket 同时从其它匹配池中移除，后续匹配池不会再看到它
+					for _, set := range sets {
+						delete(set, id)
+					}
+					m.store.setStatus(id, status)
+					m.remove(id)
+				}
+			}
+		}
+		results = append(results, rs...)
+	}
+	for i, p := range m.pools {
+		// 优先补充运行中对局的空位
+		claim(m.backfill(&p.profile, sets[i], now))
+		// 匹配在 ticket 的副本上进行，RouteAll 时前一个池留下的 used 标记不会影响本池
+		rs, ds := p.match(m.algorithm, sets[i], now, m.diagnose, m.bots)
+		for _, d := range ds {
+			diags[d.TicketId] = append(diags[d.TicketId], d)
+		}
+		claim(rs)
+	}
+	for _, set := range sets {
+		for id := range set {
+			m.store.setStatus(id, StatusQueued)
+		}
+	}
+	if m.diagnose {
+		m.diags = diags
+	}
+	m.mu.Unlock()
+
+	if m.expired != nil {
+		for _, e := range expired {
+			m.expired(e)
+		}
+	}
+	if m.ready != nil && m.ready.Cancelled != nil {
+		for _, c := range cancels {
+			m.ready.Cancelled(c)
+		}
+	}
+	for _, r := range results {
+		m.submitter(r)
+	}
+}
+
+// expire 移除已过截止时间的 ticket
+func (m *Matchmaker) expire(now int64) (expired []ExpiredTicket) {
+	timeout := func(t *Ticket, pool string, reason ExpireReason, end int64) {
+		expired = append(expired, ExpiredTicket{
+			TicketId:   t.TicketId,
+			PoolName:   pool,
+			Reason:     reason,
+			StartMatch: t.startMatch,
+			EndMatch:   end,
+		})
+		m.store.setStatus(t.TicketId, StatusExpired)
+		m.remove(t.TicketId)
+	}
+	for _, p := range m.pools {
+		for _, t := range p.tickets {
+			if t.endMatch == 0 || now < t.endMatch {
+				continue
+			}
+			reason := ExpirePoolTimeout
+			if t.MaxWait > 0 && t.startMatch+t.MaxWait == t.endMatch {
+				reason = ExpireTicketTimeout
+			}
+			timeout(t, p.profile.Name, reason, t.endMatch)
+		}
+	}
+	for _, t := range m.routed {
+		if t.endMatch > 0 && now >= t.endMatch {
+			timeout(t, "", ExpireTicketTimeout, t.endMatch)
+			continue
+		}
+		// 自动路由的 ticket 超过其所在所有匹配池的 MaxWait 才算超时
+		idx := m.routeTicket(now, t)
+		var wait int64
+		for _, i := range idx {
+			w := m.pools[i].profile.MaxWait
+			if w <= 0 {
+				wait = 0
+				break
+			}
+			wait = max(wait, w)
+		}
+		if len(idx) > 0 && wait > 0 && now >= t.startMatch+wait {
+			timeout(t, m.pools[idx[len(idx)-1]].profile.Name, ExpirePoolTimeout, t.startMatch+wait)
+		}
+	}
+	return expired
+}
+
+// remove 从匹配器中移除 ticket，不修改 store 中的状态
+func (m *Matchmaker) remove(ticketId string) {
+	delete(m.routed, ticketId)
+	for _, p := range m.pools {
+		delete(p.tickets, ticketId)
+	}
+}
+
+func (p *poolState) match(algorithm MatchAlgorithm, tickets map[string]*Ticket, now int64, diagnose bool, bots BotProvider) ([]MatchResult, []Diagnosis) {
+	pool := p.profile
+	if pool.Bots == nil {
+		pool.Bots = bots
+	}
+	if diagnose {
+		pool.diag = newDiagnostics(pool.Name, now)
+	}
+	results, remaining := runAlgorithm(algorithm, pool, tickets, now)
+	if pool.diag == nil {
+		return results, nil
+	}
+	return results, pool.diag.list(results, remaining)
+}
+
+// Diagnose 返回 ticket 在最近一轮匹配中每个匹配池的诊断结果，需要通过 WithDiagnostics 开启
+func (m *Matchmaker) Diagnose(ticketId string) []Diagnosis {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.diags[ticketId])
+}
@@ -0,0 +1,177 @@
+package fifo
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type manualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*manualTicker
+}
+
+type manualTicker struct {
+	clock *manualClock
+	d     time.Duration
+	next  time.Time
+	c     chan time.Time
+	done  chan struct{}
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{now: time.UnixMilli(1_700_000_000_000)}
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) NewTicker(d time.Duration) Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &manualTicker{clock: c, d: d, next: c.now.Add(d), c: make(chan time.Time), done: make(chan struct{})}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Advance 推进时钟，每跨过一个 tick 都会阻塞直到匹配循环收到信号
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	c.mu.Unlock()
+	for {
+		c.mu.Lock()
+		var fire *manualTicker
+		for _, t := range c.tickers {
+			if !t.next.After(end) && (fire == nil || t.next.Before(fire.next)) {
+				fire = t
+			}
+		}
+		if fire == nil {
+			c.now = end
+			c.mu.Unlock()
+			return
+		}
+		c.now = fire.next
+		fire.next = fire.next.Add(fire.d)
+		now := c.now
+		c.mu.Unlock()
+		select {
+		case fire.c <- now:
+		case <-fire.done:
+		}
+	}
+}
+
+func (t *manualTicker) C() <-chan time.Time { return t.c }
+
+func (t *manualTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	close(t.done)
+	t.clock.tickers = slices.DeleteFunc(t.clock.tickers, func(x *manualTicker) bool { return x == t })
+}
+
+func soloTicket(id string, members ...string) *Ticket {
+	t := &Ticket{TicketId: id}
+	for _, m := range members {
+		t.Members = append(t.Members, Member{MemberId: m})
+	}
+	return t
+}
+
+func Test_NewMatchmaker(t *testing.T) {
+	_, err := NewMatchmaker(MatchProfile{Tick: "abc"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidTick)
+	_, err = NewMatchmaker(MatchProfile{Tick: "-1s"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidTick)
+	_, err = NewMatchmaker(MatchProfile{Tick: "1s", Algorithm: "foo"}, nil)
+	assert.ErrorIs(t, err, ErrUnknownAlgorithm)
+	mm, err := NewMatchmaker(MatchProfile{Tick: "0.5s"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, mm.tick)
+}
+
+func Test_MatchmakerTick(t *testing.T) {
+	clock := newManualClock()
+	results := make(chan MatchResult, 10)
+	mm, err := NewMatchmaker(MatchProfile{
+		Name: "test",
+		Tick: "0.5s",
+		Pools: []PoolProfile{{
+			Name:             "duo",
+			Teams:            []string{"a"},
+			TeamMembers:      2,
+			MaxMatchPerRound: 10,
+		}},
+	}, func(r MatchResult) { results <- r }, WithClock(clock))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, mm.Enqueue("none", soloTicket("0", "0_1")), ErrUnknownPool)
+	assert.NoError(t, mm.Enqueue("duo", soloTicket("1", "1_1")))
+	assert.NoError(t, mm.Start())
+	assert.ErrorIs(t, mm.Start(), ErrRunning)
+
+	clock.Advance(time.Second)
+	assert.Len(t, results, 0)
+
+	assert.NoError(t, mm.Enqueue("duo", soloTicket("2", "2_1")))
+	clock.Advance(500 * time.Millisecond)
+	mm.Stop()
+	assert.Len(t, results, 1)
+	r := <-results
+	assert.Equal(t, "duo", r.PoolName)
+	assert.ElementsMatch(t, []string{"1", "2"}, r.Teams[0].TicketId)
+	assert.Empty(t, mm.pools[0].tickets)
+	info, ok := mm.Get("1")
+	assert.True(t, ok)
+	assert.Equal(t, StatusMatched, info.Status)
+}
+
+func Test_MatchmakerExpire(t *testing.T) {
+	clock := newManualClock()
+	var expired []ExpiredTicket
+	mm, err := NewMatchmaker(MatchProfile{
+		Tick: "1s",
+		Pools: []PoolProfile{{
+			Name:             "duo",
+			Teams:            []string{"a"},
+			TeamMembers:      2,
+			MaxMatchPerRound: 10,
+			MaxWait:          5000,
+		}},
+	}, func(MatchResult) {}, WithClock(clock), WithExpiredSubmitter(func(e ExpiredTicket) {
+		expired = append(expired, e)
+	}))
+	assert.NoError(t, err)
+	short := soloTicket("1", "1_1")
+	short.MaxWait = 2000
+	assert.NoError(t, mm.Enqueue("duo", short))
+	long := soloTicket("2", "2_2", "2_3", "2_4")
+	long.MaxWait = 10000
+	assert.NoError(t, mm.Enqueue("duo", long))
+
+	clock.Advance(2 * time.Second)
+	mm.Tick()
+	assert.Equal(t, []ExpiredTicket{{
+		TicketId:   "1",
+		PoolName:   "duo",
+		Reason:     ExpireTicketTimeout,
+		StartMatch: clock.Now().UnixMilli() - 2000,
+		EndMatch:   clock.Now().UnixMilli(),
+	}}, expired)
+
+	clock.Advance(3 * time.Second)
+	mm.Tick()
+	assert.Len(t, expired, 2)
+	assert.Equal(t, ExpirePoolTimeout, expired[1].Reason)
+	info, _ := mm.Get("2")
+	assert.Equal(t, StatusExpired, info.Status)
+	assert.Empty(t, mm.pools[0].tickets)
+}
@@ -0,0 +1,122 @@
+package fifo
+
+import (
+	"math"
+	"slices"
+)
+
+const (
+	MembersAll = "all" // 每个队员都满足过滤器
+	MembersAny = "any" // 至少一个队员满足过滤器
+)
+
+const (
+	PartyMax  = "max"   // 队员中的最大值
+	PartyMin  = "min"   // 队员中的最小值
+	PartyAvg  = "avg"   // 队员的平均值
+	PartyTopN = "top_n" // 从高到低前 len(Weights) 名队员按 Weights 加权平均
+)
+
+// PartyArg 将队员的 IntArgs/FloatArgs 聚合为 ticket 的同名参数，供过滤器、Rule、Balance、补充等使用。
+// 没有该参数的队员不参与聚合，ticket 自身已有该参数时不覆盖。
+// 聚合结果同时作为 FloatArg 与四舍五入后的 IntArg 提供。
+type PartyArg struct {
+	Arg     string    `json:"arg"`
+	Mode    string    `json:"mode"`    // PartyMax 等
+	Weights []float64 `json:"weights"` // PartyTopN 使用，如 [0.5, 0.3, 0.2]
+}
+
+// value 聚合 t 的队员参数，没有队员有该参数时返回 false
+func (a *PartyArg) value(t *Ticket) (float64, bool) {
+	vs := make([]float64, 0, len(t.Members))
+	for i := range t.Members {
+		if v, ok := memberValue(&t.Members[i], a.Arg); ok {
+			vs = append(vs, v)
+		}
+	}
+	if len(vs) == 0 {
+		return 0, false
+	}
+	switch a.Mode {
+	case PartyMax:
+		return slices.Max(vs), true
+	case PartyMin:
+		return slices.Min(vs), true
+	case PartyTopN:
+		slices.SortFunc(vs, func(x, y float64) int {
+			return compareFloat(y, x)
+		})
+		var sum, weight float64
+		for i, w := range a.Weights[:min(len(a.Weights), len(vs))] {
+			sum += vs[i] * w
+			weight += w
+		}
+		if weight == 0 {
+			return 0, false
+		}
+		return sum / weight, true
+	}
+	var sum float64
+	for _, v := range vs {
+		sum += v
+	}
+	return sum / float64(len(vs)), true
+}
+
+func compareFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// memberValue 队员的数值参数，先查找 IntArgs 再查找 FloatArgs
+func memberValue(m *Member, arg string) (float64, bool) {
+	if i, ok := findInt(m.IntArgs, arg); ok {
+		return float64(i), true
+	}
+	return findFloat(m.FloatArgs, arg)
+}
+
+// party 返回加上 PartyArgs 聚合参数的 ticket 浅拷贝，没有需要补充的参数时返回 t 本身
+func (p *PoolProfile) party(t *Ticket) *Ticket {
+	c := t
+	for i := range p.PartyArgs {
+		a := &p.PartyArgs[i]
+		if _, ok := findInt(t.IntArgs, a.Arg); ok {
+			continue
+		}
+		if _, ok := findFloat(t.FloatArgs, a.Arg); ok {
+			continue
+		}
+		v, ok := a.value(t)
+		if !ok {
+			continue
+		}
+		if c == t {
+			cp := *t
+			cp.IntArgs = slices.Clip(t.IntArgs)
+			cp.FloatArgs = slices.Clip(t.FloatArgs)
+			c = &cp
+		}
+		c.IntArgs = append(c.IntArgs, IntArg{a.Arg, int64(math.Round(v))})
+		c.FloatArgs = append(c.FloatArgs, FloatArg{a.Arg, v})
+	}
+	return c
+}
+
+// quantify 按 MembersAll 或 MembersAny 检查 ticket 的队员
+func quantify(t *Ticket, mode string, f func(m *Member) bool) bool {
+	if mode == MembersAny {
+		return slices.ContainsFunc(t.Members, func(m Member) bool { return f(&m) })
+	}
+	for i := range t.Members {
+		if !f(&t.Members[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package fifo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PartyArgs(t *testing.T) {
+	ti := &Ticket{Members: []Member{
+		{MemberId: "1", IntArgs: []IntArg{{"mmr", 1000}}},
+		{MemberId: "2", IntArgs: []IntArg{{"mmr", 2000}}},
+		{MemberId: "3", FloatArgs: []FloatArg{{"mmr", 1500}}},
+		{MemberId: "4"},
+	}}
+	for mode, want := range map[string]float64{
+		PartyMax: 2000,
+		PartyMin: 1000,
+		PartyAvg: 1500,
+	} {
+		v, ok := (&PartyArg{Arg: "mmr", Mode: mode}).value(ti)
+		assert.True(t, ok, mode)
+		assert.Equal(t, want, v, mode)
+	}
+	// 前两名 2000、1500 按 3:1 加权
+	v, _ := (&PartyArg{Arg: "mmr", Mode: PartyTopN, Weights: []float64{3, 1}}).value(ti)
+	assert.Equal(t, float64(1875), v)
+	_, ok := (&PartyArg{Arg: "level", Mode: PartyMax}).value(ti)
+	assert.False(t, ok)
+
+	pool := PoolProfile{PartyArgs: []PartyArg{{Arg: "mmr", Mode: PartyTopN, Weights: []float64{3, 1}}}}
+	c := pool.party(ti)
+	assert.Equal(t, []IntArg{{"mmr", 1875}}, c.IntArgs)
+	assert.Empty(t, ti.IntArgs)
+	// ticket 自身的参数优先
+	ti.IntArgs = []IntArg{{"mmr", 100}}
+	assert.Same(t, ti, pool.party(ti))
+	// 队员参数只通过 PartyArgs 聚合，不能作为伪参数
+	_, ok = lookupPseudo("$max.mmr")
+	assert.False(t, ok)
+}
+
+func Test_MemberFilters(t *testing.T) {
+	p, err := ParseProfile([]byte(`{"pools": [{
+		"name": "a",
+		"string_filters": [{"arg": "dlc", "op": "=", "value": "yes", "members": "all"}],
+		"int_filters": [{"arg": "level", "min": 30, "max": 100, "members": "any"}],
+		"party_args": [{"arg": "mmr", "mode": "max"}],
+		"rule": "mmr >= 1500",
+		"teams": ["a"], "team_members": 2, "max_match_per_round": 1
+	}]}`), "json")
+	assert.NoError(t, err)
+	pool, err := p.Pools[0].Compile()
+	assert.NoError(t, err)
+	ti := &Ticket{Members: []Member{
+		{MemberId: "1", StringArgs: []StringArg{{"dlc", "yes"}}, IntArgs: []IntArg{{"level", 10}, {"mmr", 1600}}},
+		{MemberId: "2", StringArgs: []StringArg{{"dlc", "yes"}}, IntArgs: []IntArg{{"level", 40}, {"mmr", 1200}}},
+	}}
+	assert.True(t, pool.Allow(0, ti))
+	ti.Members[0].IntArgs[1].Value = 1400
+	field, _ := pool.reject(0, ti)
+	assert.Equal(t, "rule", field)
+	ti.Members[0].IntArgs[1].Value = 1600
+	ti.Members[1].IntArgs[0].Value = 20
+	field, _ = pool.reject(0, ti)
+	assert.Equal(t, "int_filters[0]", field)
+	ti.Members[1].StringArgs = nil
+	field, _ = pool.reject(0, ti)
+	assert.Equal(t, "string_filters[0]", field)
+
+	p.Pools[0].IntFilters[0].Members = "some"
+	p.Pools[0].PartyArgs[0].Mode = PartyTopN
+	var es ValidationErrors
+	assert.ErrorAs(t, p.Validate(), &es)
+	var fields []string
+	for _, e := range es {
+		fields = append(fields, e.Field)
+	}
+	assert.ElementsMatch(t, []string{"int_filters[0].members", "party_args[0].weights"}, fields)
+}
+
+func Test_BackfillPartyArgs(t *testing.T) {
+	clock := newManualClock()
+	var results []MatchResult
+	mm, err := NewMatchmaker(MatchProfile{
+		Tick: "1s",
+		Pools: []PoolProfile{{
+			Name:             "trio",
+			Teams:            []string{"a"},
+			TeamMembers:      3,
+			MaxMatchPerRound: 10,
+			PartyArgs:        []PartyArg{{Arg: "mmr", Mode: PartyMax}},
+			PairConstraints:  []PairConstraint{{Kind: PairIntDiff, Arg: "mmr", MaxDiff: 100}},
+		}},
+	}, func(r MatchResult) {
+		results = append(results, r)
+	}, WithClock(clock))
+	assert.NoError(t, err)
+	for i, mmr := range []int64{1000, 3000, 1050} {
+		ti := soloTicket(string(rune('1'+i)), string(rune('1'+i))+"_1")
+		ti.Members[0].IntArgs = []IntArg{{"mmr", mmr}}
+		assert.NoError(t, mm.Enqueue("trio", ti))
+		clock.Advance(time.Millisecond)
+	}
+	assert.NoError(t, mm.AddBackfill(Backfill{BackfillId: "game1", PoolName: "trio", Teams: []BackfillTeam{{TeamName: "a", Open: 2}}}))
+	mm.Tick()
+	// 两两约束使用由队员聚合出的 mmr
+	assert.Len(t, results, 1)
+	assert.Equal(t, "game1", results[0].BackfillId)
+	assert.Equal(t, []string{"1", "3"}, results[0].Teams[0].TicketId)
+}
@@ -0,0 +1,69 @@
+package fifo
+
+import (
+	"math"
+
+	"github.com/LeGamerDc/matching/mwm"
+)
+
+// mwmWeight 两队匹配分的基准值，匹配分 = mwmWeight - |两队 QualityArg 差值|
+const mwmWeight = 1 << 20
+
+// MwmMatch 与 FifoMatch 相同地组出队伍后，用最大权匹配 (mwm.B5) 两两配对队伍，使整体匹配分最高。
+// 仅对两队且未使用 TeamSlots 的匹配池生效，其余情况退化为 FifoMatch。
+func MwmMatch(pool PoolProfile, tickets map[string]*Ticket, now int64, r ResultSubmitter) {
+	if len(pool.TeamSlots) > 0 || len(pool.Teams) != 2 {
+		FifoMatch(pool, tickets, now, r)
+		return
+	}
+	cans := buildCandidates(pool, tickets, now)
+	if len(cans) < 2 {
+		diagnoseTeams(pool.diag, cans)
+		return
+	}
+	rating := make([]float64, len(cans))
+	for i, can := range cans {
+		rating[i] = can.rating(pool.QualityArg)
+	}
+	b := mwm.New(len(cans))
+	for i := range cans {
+		for j := i + 1; j < len(cans); j++ {
+			if !cans[j].matchTeam(cans[i : i+1]) {
+				continue
+			}
+			w := mwmWeight - int(math.Abs(rating[i]-rating[j]))
+			b.AddEdge(i+1, j+1, max(w, 1))
+		}
+	}
+	matched, _, _ := b.Solve()
+	for _, pair := range matched {
+		x, y := cans[pair[0]-1], cans[pair[1]-1]
+		x.used, y.used = true, true
+		r(matchResult(pool, pool.Balance.apply([]*candidate{x, y})))
+	}
+	diagnoseTeams(pool.diag, cans)
+}
+
+// rating 队伍内各 ticket 的 arg 按人数加权平均，arg 为 IntArg 或 FloatArg
+func (c *candidate) rating(arg string) float64 {
+	if arg == "" {
+		return 0
+	}
+	var sum, n float64
+	for _, t := range c.tickets {
+		v, ok := findFloat(t.FloatArgs, arg)
+		if !ok {
+			i, ok := findInt(t.IntArgs, arg)
+			if !ok {
+				continue
+			}
+			v = float64(i)
+		}
+		sum += v * float64(len(t.Members))
+		n += float64(len(t.Members))
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / n
+}
@@ -0,0 +1,57 @@
+package fifo
+
+import (
+	"math"
+	"slices"
+)
+
+const (
+	PairIntDiff         = "int_diff"         // 两个 ticket 的 IntArg 差值不超过 MaxDiff
+	PairFloatDiff       = "float_diff"       // 两个 ticket 的 FloatArg 差值不超过 MaxDiff
+	PairStringEqual     = "string_equal"     // 两个 ticket 的 StringArg 相等
+	PairStringIntersect = "string_intersect" // 两个 ticket 同名 StringArg 的取值集合有交集，同一个 Key 可以出现多次
+)
+
+// hateConflict candidate.allow 因黑名单拒绝时的返回值
+const hateConflict = "allow_hate"
+
+// PairConstraint 同队 ticket 两两之间的约束，缺少 Arg 的 ticket 视为不满足
+type PairConstraint struct {
+	Arg     string  `json:"arg"`
+	Kind    string  `json:"kind"`
+	MaxDiff float64 `json:"max_diff"` // int_diff/float_diff 允许的最大差值
+	Relax   *Relax  `json:"relax"`    // 随等待时间放宽 MaxDiff，按两个 ticket 中等待较久者计算
+}
+
+func (pc *PairConstraint) allow(now int64, a, b *Ticket) bool {
+	switch pc.Kind {
+	case PairIntDiff:
+		x, ok1 := findInt(a.IntArgs, pc.Arg)
+		y, ok2 := findInt(b.IntArgs, pc.Arg)
+		return ok1 && ok2 && math.Abs(float64(x-y)) <= pc.maxDiff(now, a, b)
+	case PairFloatDiff:
+		x, ok1 := findFloat(a.FloatArgs, pc.Arg)
+		y, ok2 := findFloat(b.FloatArgs, pc.Arg)
+		return ok1 && ok2 && math.Abs(x-y) <= pc.maxDiff(now, a, b)
+	case PairStringEqual:
+		x, ok1 := findString(a.StringArgs, pc.Arg)
+		y, ok2 := findString(b.StringArgs, pc.Arg)
+		return ok1 && ok2 && x == y
+	case PairStringIntersect:
+		for _, x := range a.StringArgs {
+			if x.Key == pc.Arg && slices.Contains(b.StringArgs, StringArg{pc.Arg, x.Value}) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (pc *PairConstraint) maxDiff(now int64, a, b *Ticket) float64 {
+	older := a
+	if b.startMatch < a.startMatch {
+		older = b
+	}
+	return pc.MaxDiff + pc.Relax.widen(now, older)
+}
@@ -0,0 +1,64 @@
+package fifo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PairConstraint(t *testing.T) {
+	a := &Ticket{
+		IntArgs:    []IntArg{{"mmr", 500}},
+		StringArgs: []StringArg{{"region", "eu"}, {"lang", "en"}, {"lang", "fr"}},
+	}
+	b := &Ticket{
+		IntArgs:    []IntArg{{"mmr", 800}},
+		StringArgs: []StringArg{{"region", "us"}, {"lang", "fr"}},
+		startMatch: 1000,
+	}
+	diff := PairConstraint{Arg: "mmr", Kind: PairIntDiff, MaxDiff: 200, Relax: &Relax{Every: 10000, Step: 100}}
+	assert.False(t, diff.allow(0, a, b))
+	assert.True(t, diff.allow(10000, a, b))
+	assert.False(t, (&PairConstraint{Arg: "kd", Kind: PairFloatDiff, MaxDiff: 1}).allow(0, a, b))
+	assert.False(t, (&PairConstraint{Arg: "region", Kind: PairStringEqual}).allow(0, a, b))
+	assert.True(t, (&PairConstraint{Arg: "lang", Kind: PairStringIntersect}).allow(0, a, b))
+	b.StringArgs = b.StringArgs[:1]
+	assert.False(t, (&PairConstraint{Arg: "lang", Kind: PairStringIntersect}).allow(0, a, b))
+}
+
+func Test_FifoMatchPairConstraint(t *testing.T) {
+	tickets := make(map[string]*Ticket)
+	for i, mmr := range []int64{500, 3000, 600, 2900} {
+		ti := soloTicket(string(rune('a'+i)), string(rune('a'+i)))
+		ti.IntArgs = []IntArg{{"mmr", mmr}}
+		ti.startMatch = int64(i)
+		tickets[ti.TicketId] = ti
+	}
+	pool := PoolProfile{
+		Teams:            []string{"a"},
+		TeamMembers:      2,
+		MaxMatchPerRound: 10,
+		PairConstraints:  []PairConstraint{{Arg: "mmr", Kind: PairIntDiff, MaxDiff: 200}},
+	}
+	results, _, diags := Explain(pool, tickets, 0)
+	var teams [][]string
+	for _, r := range results {
+		teams = append(teams, r.Teams[0].TicketId)
+	}
+	assert.ElementsMatch(t, [][]string{{"a", "c"}, {"b", "d"}}, teams)
+	assert.Len(t, diags, 4)
+
+	delete(tickets, "c")
+	delete(tickets, "d")
+	_, _, diags = Explain(pool, tickets, 0)
+	assert.Equal(t, []Diagnosis{
+		{TicketId: "a", Reason: ReasonPairConstraint, Detail: "pair_constraints[0]"},
+		{TicketId: "b", Reason: ReasonPairConstraint, Detail: "pair_constraints[0]"},
+	}, diags)
+
+	p := MatchProfile{Pools: []PoolProfile{pool}}
+	p.Pools[0].PairConstraints[0].Kind = "near"
+	var es ValidationErrors
+	assert.ErrorAs(t, p.Validate(), &es)
+	assert.Equal(t, "pair_constraints[0].kind", es[0].Field)
+}
@@ -0,0 +1,373 @@
+package fifo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrUnknownFormat = errors.New("fifo: unknown profile format")
+
+// LoadProfile 从 .json/.yaml/.yml 文件读取匹配场配置并校验编译，未知字段视为错误
+func LoadProfile(path string) (MatchProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return MatchProfile{}, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ParseProfile(data, "json")
+	case ".yaml", ".yml":
+		return ParseProfile(data, "yaml")
+	}
+	return MatchProfile{}, fmt.Errorf("%w: %s", ErrUnknownFormat, path)
+}
+
+// ParseProfile 解析 json 或 yaml 格式的匹配场配置并校验，返回的匹配池已编译，见 PoolProfile.Compile。
+// yaml 先转换为 json 再解析，因此两种格式使用相同的 json 字段名。
+func ParseProfile(data []byte, format string) (MatchProfile, error) {
+	var p MatchProfile
+	switch format {
+	case "json":
+	case "yaml":
+		var v any
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return p, err
+		}
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return p, err
+		}
+	default:
+		return p, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return p, err
+	}
+	if dec.More() {
+		return p, errors.New("fifo: unexpected data after profile")
+	}
+	return p.compile()
+}
+
+// ValidationError 单个配置错误，Field 使用 json 字段路径，如 "int_filters[0]"
+type ValidationError struct {
+	Pool   string
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Pool == "" {
+		return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+	}
+	return fmt.Sprintf("pool %q: %s: %s", e.Pool, e.Field, e.Reason)
+}
+
+// ValidationErrors Validate 返回的全部配置错误
+type ValidationErrors []*ValidationError
+
+func (es ValidationErrors) Error() string {
+	s := make([]string, 0, len(es))
+	for _, e := range es {
+		s = append(s, e.Error())
+	}
+	return strings.Join(s, "; ")
+}
+
+// Validate 校验配置，有错误时返回 ValidationErrors，不修改配置
+func (p *MatchProfile) Validate() error {
+	var es ValidationErrors
+	names := make(map[string]struct{}, len(p.Pools))
+	for i := range p.Pools {
+		pool := &p.Pools[i]
+		if _, ok := names[pool.Name]; ok {
+			es = append(es, &ValidationError{Pool: pool.Name, Field: fmt.Sprintf("pools[%d].name", i), Reason: "duplicate pool name"})
+		}
+		names[pool.Name] = struct{}{}
+		es = append(es, pool.validate()...)
+	}
+	if len(es) > 0 {
+		return es
+	}
+	return nil
+}
+
+func (p *PoolProfile) validate() (es ValidationErrors) {
+	fail := func(field, format string, args ...any) {
+		es = append(es, &ValidationError{Pool: p.Name, Field: field, Reason: fmt.Sprintf(format, args...)})
+	}
+	size := p.maxMembers()
+	if len(p.TeamSlots) > 0 {
+		if len(p.Teams) > 0 || p.TeamMembers != 0 || p.MaxTeamMembers != 0 {
+			fail("team_slots", "must not be used with teams, team_members or max_team_members")
+		}
+		size = 0
+		for i := range p.TeamSlots {
+			s := &p.TeamSlots[i]
+			field := fmt.Sprintf("team_slots[%d]", i)
+			if s.Name == "" {
+				fail(field+".name", "must not be empty")
+			}
+			if s.Members <= 0 {
+				fail(field+".members", "must be positive, got %d", s.Members)
+			}
+			if size == 0 || s.Members < size {
+				size = s.Members
+			}
+			validateFilters(field+".", s.StringFilters, s.IntFilters, s.FloatFilters, fail)
+		}
+	} else {
+		switch {
+		case p.MaxTeamMembers < 0:
+			fail("max_team_members", "must not be negative, got %d", p.MaxTeamMembers)
+		case p.MaxTeamMembers > 0 && p.TeamMembers != 0 && p.MaxTeamMembers != p.TeamMembers:
+			fail("max_team_members", "must equal team_members when both are set, got %d and %d", p.MaxTeamMembers, p.TeamMembers)
+		case p.maxMembers() <= 0:
+			fail("team_members", "must be positive, got %d", p.TeamMembers)
+		}
+		if len(p.Teams) == 0 {
+			fail("teams", "must not be empty")
+		}
+	}
+	if p.MaxMatchPerRound <= 0 {
+		fail("max_match_per_round", "must be positive, got %d", p.MaxMatchPerRound)
+	}
+	validateFilters("", p.StringFilters, p.IntFilters, p.FloatFilters, fail)
+	for i, pc := range p.PairConstraints {
+		field := fmt.Sprintf("pair_constraints[%d]", i)
+		switch pc.Kind {
+		case PairIntDiff, PairFloatDiff:
+			if pc.MaxDiff < 0 {
+				fail(field, "max_diff must not be negative, got %g", pc.MaxDiff)
+			}
+		case PairStringEqual, PairStringIntersect:
+		default:
+			fail(field+".kind", "unknown kind %q", pc.Kind)
+		}
+		if reason := pc.Relax.validate(); reason != "" {
+			fail(field+".relax", "%s", reason)
+		}
+	}
+	if p.MinTeamMembers != 0 {
+		switch {
+		case len(p.TeamSlots) > 0:
+			fail("min_team_members", "must not be used with team_slots")
+		case p.MinTeamMembers < 0 || p.MinTeamMembers > p.maxMembers():
+			fail("min_team_members", "must be in [1, %d], got %d", p.maxMembers(), p.MinTeamMembers)
+		}
+	} else if p.MinTeamRelax != nil {
+		fail("min_team_relax", "requires min_team_members")
+	}
+	if reason := p.MinTeamRelax.validate(); reason != "" {
+		fail("min_team_relax", "%s", reason)
+	}
+	if p.Filter != nil {
+		p.Filter.validate("filter", fail)
+	}
+	if p.Rule != "" {
+		if _, err := compileRule(p.Rule); err != nil {
+			fail("rule", "%s", err)
+		}
+	}
+	for i, a := range p.PartyArgs {
+		field := fmt.Sprintf("party_args[%d]", i)
+		if a.Arg == "" {
+			fail(field+".arg", "must not be empty")
+		}
+		switch a.Mode {
+		case PartyMax, PartyMin, PartyAvg:
+		case PartyTopN:
+			if len(a.Weights) == 0 {
+				fail(field+".weights", "must not be empty for mode %q", a.Mode)
+			}
+			for _, w := range a.Weights {
+				if w < 0 {
+					fail(field+".weights", "must not be negative, got %g", w)
+					break
+				}
+			}
+		default:
+			fail(field+".mode", "unknown mode %q", a.Mode)
+		}
+	}
+	if p.BotAfter < 0 {
+		fail("bot_after", "must not be negative, got %d", p.BotAfter)
+	}
+	roles, total := make(map[string]struct{}), 0
+	for i, q := range p.RoleQuotas {
+		field := fmt.Sprintf("role_quotas[%d]", i)
+		if q.Role == "" {
+			fail(field+".role", "must not be empty")
+		} else if _, ok := roles[q.Role]; ok {
+			fail(field+".role", "duplicate role %q", q.Role)
+		}
+		roles[q.Role] = struct{}{}
+		if q.Count <= 0 {
+			fail(field+".count", "must be positive, got %d", q.Count)
+		}
+		total += q.Count
+	}
+	if total > size && size > 0 {
+		fail("role_quotas", "total count %d exceeds team size %d", total, size)
+	}
+	if b := p.Balance; b != nil {
+		if b.Arg == "" {
+			fail("balance.arg", "must not be empty")
+		}
+		switch b.Mode {
+		case "", BalanceAvg, BalanceSum:
+		default:
+			fail("balance.mode", "unknown mode %q", b.Mode)
+		}
+	}
+	return es
+}
+
+// Compile 校验匹配池配置，返回编译了正则表达式等运行时状态的副本，p 本身不变。
+// 未编译的配置同样可以调用 Allow，但每次调用都要重新编译；ParseProfile、LoadProfile 与 Matchmaker 会自动编译。
+// 伪参数在编译时解析，之后注册的伪参数对已编译的配置不生效。
+func (p PoolProfile) Compile() (PoolProfile, error) {
+	if es := p.validate(); len(es) > 0 {
+		return p, es
+	}
+	p.compile()
+	return p, nil
+}
+
+// compile 在新的过滤器切片上编译运行时状态，与调用方共享底层数组的配置不受影响
+func (p *PoolProfile) compile() {
+	p.StringFilters, p.IntFilters, p.FloatFilters = compileFilters(p.StringFilters, p.IntFilters, p.FloatFilters)
+	p.TeamSlots = slices.Clone(p.TeamSlots)
+	for i := range p.TeamSlots {
+		s := &p.TeamSlots[i]
+		s.StringFilters, s.IntFilters, s.FloatFilters = compileFilters(s.StringFilters, s.IntFilters, s.FloatFilters)
+	}
+	if p.Filter != nil {
+		p.filter = p.Filter.compile()
+	}
+	if p.Rule != "" {
+		// 编译错误已由 validate 返回
+		p.rule, _ = compileRule(p.Rule)
+	}
+	p.compiled = true
+}
+
+// compile 校验配置并返回编译后的副本，Matchmaker 持有该副本，调用方的配置不变
+func (p *MatchProfile) compile() (MatchProfile, error) {
+	if err := p.Validate(); err != nil {
+		return *p, err
+	}
+	c := *p
+	c.Pools = slices.Clone(p.Pools)
+	for i := range c.Pools {
+		c.Pools[i].compile()
+	}
+	return c, nil
+}
+
+func compileFilters(sf []StringFilter, inf []IntFilter, ff []FloatFilter) ([]StringFilter, []IntFilter, []FloatFilter) {
+	sf, inf, ff = slices.Clone(sf), slices.Clone(inf), slices.Clone(ff)
+	for i := range sf {
+		_ = sf[i].compile()
+	}
+	for i := range inf {
+		inf[i].compile()
+	}
+	for i := range ff {
+		ff[i].compile()
+	}
+	return sf, inf, ff
+}
+
+// validateFilters 校验一组过滤器，prefix 为字段路径前缀，如 "team_slots[0]."
+func validateFilters(prefix string, sf []StringFilter, inf []IntFilter, ff []FloatFilter, fail func(field, format string, args ...any)) {
+	for i := range sf {
+		sf[i].validate(fmt.Sprintf("%sstring_filters[%d]", prefix, i), fail)
+	}
+	for i := range inf {
+		inf[i].validate(fmt.Sprintf("%sint_filters[%d]", prefix, i), fail)
+	}
+	for i := range ff {
+		ff[i].validate(fmt.Sprintf("%sfloat_filters[%d]", prefix, i), fail)
+	}
+}
+
+func (f *StringFilter) validate(field string, fail func(field, format string, args ...any)) {
+	validateMembers(field, f.Arg, f.Members, fail)
+	switch f.Op {
+	case EqualOp, NotEqualOp, PrefixOp, SuffixOp, ExistsOp, MissingOp:
+	case InOp, NotInOp:
+		if len(f.Values) == 0 {
+			fail(field+".values", "must not be empty for op %q", f.Op)
+		}
+	case RegexOp:
+		if _, err := regexp.Compile(f.Value); err != nil {
+			fail(field+".value", "%s", err)
+		}
+	default:
+		fail(field+".op", "unknown op %q", f.Op)
+	}
+}
+
+func (f *IntFilter) validate(field string, fail func(field, format string, args ...any)) {
+	validatePseudo(field, f.Arg, fail)
+	validateMembers(field, f.Arg, f.Members, fail)
+	if f.Min > f.Max {
+		fail(field, "min %d > max %d", f.Min, f.Max)
+	}
+	if reason := f.Relax.validate(); reason != "" {
+		fail(field+".relax", "%s", reason)
+	}
+}
+
+func (f *FloatFilter) validate(field string, fail func(field, format string, args ...any)) {
+	validatePseudo(field, f.Arg, fail)
+	validateMembers(field, f.Arg, f.Members, fail)
+	if f.Min > f.Max {
+		fail(field, "min %g > max %g", f.Min, f.Max)
+	}
+	if reason := f.Relax.validate(); reason != "" {
+		fail(field+".relax", "%s", reason)
+	}
+}
+
+func validatePseudo(field, arg string, fail func(field, format string, args ...any)) {
+	if _, ok := lookupPseudo(arg); isPseudo(arg) && !ok {
+		fail(field+".arg", "unknown pseudo argument %s", arg)
+	}
+}
+
+func validateMembers(field, arg, members string, fail func(field, format string, args ...any)) {
+	switch {
+	case members == "":
+	case members != MembersAll && members != MembersAny:
+		fail(field+".members", "unknown members %q", members)
+	case isPseudo(arg):
+		fail(field+".members", "cannot be used with pseudo argument %s", arg)
+	}
+}
+
+func (r *Relax) validate() string {
+	switch {
+	case r == nil:
+		return ""
+	case r.Every <= 0:
+		return fmt.Sprintf("every must be positive, got %d", r.Every)
+	case r.Step < 0:
+		return fmt.Sprintf("step must not be negative, got %g", r.Step)
+	case r.Limit < 0:
+		return fmt.Sprintf("limit must not be negative, got %g", r.Limit)
+	}
+	return ""
+}
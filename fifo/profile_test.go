@@ -0,0 +1,136 @@
+package fifo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const jsonProfile = `{
+	"name": "ranked",
+	"algorithm": "fifo",
+	"tick": "0.5s",
+	"pools": [{
+		"name": "5v5",
+		"string_filters": [{"arg": "region", "op": "=", "value": "eu"}],
+		"int_filters": [{"arg": "mmr", "min": 1000, "max": 2000}],
+		"teams": ["red", "blue"],
+		"team_members": 5,
+		"max_match_per_round": 100
+	}]
+}`
+
+const yamlProfile = `
+name: ranked
+algorithm: fifo
+tick: 0.5s
+pools:
+  - name: 5v5
+    string_filters:
+      - {arg: region, op: "=", value: eu}
+    int_filters:
+      - {arg: mmr, min: 1000, max: 2000}
+    teams: [red, blue]
+    team_members: 5
+    max_match_per_round: 100
+`
+
+func writeProfile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func Test_LoadProfile(t *testing.T) {
+	pj, err := LoadProfile(writeProfile(t, "p.json", jsonProfile))
+	assert.NoError(t, err)
+	py, err := LoadProfile(writeProfile(t, "p.yml", yamlProfile))
+	assert.NoError(t, err)
+	assert.Equal(t, pj, py)
+	assert.Equal(t, int64(2000), pj.Pools[0].IntFilters[0].Max)
+	assert.Equal(t, []string{"red", "blue"}, pj.Pools[0].Teams)
+
+	_, err = LoadProfile(writeProfile(t, "p.toml", jsonProfile))
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+	_, err = ParseProfile([]byte(`{"name": "x", "tik": "1s"}`), "json")
+	assert.ErrorContains(t, err, "tik")
+	_, err = ParseProfile([]byte("name: x\ntik: 1s\n"), "yaml")
+	assert.ErrorContains(t, err, "tik")
+}
+
+func Test_Validate(t *testing.T) {
+	p := MatchProfile{Pools: []PoolProfile{{
+		Name:          "a",
+		StringFilters: []StringFilter{{Arg: "region", Op: "=="}},
+		IntFilters:    []IntFilter{{Arg: "mmr", Min: 2, Max: 1}},
+		FloatFilters:  []FloatFilter{{Arg: "kd", Min: 2, Max: 1}},
+	}, {
+		Name:             "a",
+		Teams:            []string{"a"},
+		TeamMembers:      1,
+		MaxMatchPerRound: 1,
+	}}}
+	err := p.Validate()
+	var es ValidationErrors
+	assert.True(t, errors.As(err, &es))
+	var fields []string
+	for _, e := range es {
+		fields = append(fields, e.Field)
+	}
+	assert.ElementsMatch(t, []string{
+		"team_members", "teams", "max_match_per_round", "string_filters[0].op",
+		"int_filters[0]", "float_filters[0]", "pools[1].name",
+	}, fields)
+
+	_, err = NewMatchmaker(p, nil)
+	assert.True(t, errors.As(err, &es))
+}
+
+func Test_FilterExpr(t *testing.T) {
+	p, err := ParseProfile([]byte(`
+pools:
+  - name: cross
+    filter:
+      all_of:
+        - any_of:
+            - string: {arg: platform, op: "=", value: pc}
+            - string: {arg: crossplay, op: "=", value: "on"}
+        - not:
+            int: {arg: mmr, min: 0, max: 999}
+    teams: [a]
+    team_members: 1
+    max_match_per_round: 1
+`), "yaml")
+	assert.NoError(t, err)
+	pool := p.Pools[0]
+	assert.NotNil(t, pool.filter)
+	ti := func(platform, crossplay string, mmr int64) *Ticket {
+		return &Ticket{
+			StringArgs: []StringArg{{"platform", platform}, {"crossplay", crossplay}},
+			IntArgs:    []IntArg{{"mmr", mmr}},
+		}
+	}
+	assert.True(t, pool.Allow(0, ti("pc", "off", 1500)))
+	assert.True(t, pool.Allow(0, ti("ps", "on", 1500)))
+	assert.False(t, pool.Allow(0, ti("ps", "off", 1500)))
+	field, _ := pool.reject(0, ti("pc", "on", 500))
+	assert.Equal(t, "filter", field)
+
+	// 未经 Compile 的配置不能使用
+	// 未经 Compile 的配置同样生效
+	raw := pool
+	raw.filter = nil
+	assert.True(t, raw.Allow(0, ti("ps", "on", 1500)))
+	assert.False(t, raw.Allow(0, ti("ps", "off", 1500)))
+
+	p.Pools[0].Filter = &FilterExpr{
+		AnyOf: []FilterExpr{{}, {Not: &FilterExpr{String: &StringFilter{Arg: "a", Op: "~"}}}},
+	}
+	var es ValidationErrors
+	assert.ErrorAs(t, p.Validate(), &es)
+	assert.Equal(t, "filter.any_of[0]", es[0].Field)
+	assert.Equal(t, "filter.any_of[1].not.string.op", es[1].Field)
+}
@@ -0,0 +1,118 @@
+package fifo
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PseudoArg 由 ticket 计算出的伪参数，名字以 $ 开头，可用于 IntFilter、FloatFilter 与 Rule。
+// 用于 IntFilter 时结果向零取整，ok 为 false 表示 ticket 没有该参数。
+type PseudoArg func(now int64, t *Ticket) (v float64, ok bool)
+
+// MemberAttr 队员级属性，通过 $min.<name>、$max.<name>、$avg.<name> 在 ticket 的队员间聚合为伪参数，
+// 没有该属性的队员不参与聚合。队员的 IntArgs/FloatArgs 通过 PoolProfile.PartyArgs 聚合。
+type MemberAttr func(m *Member) (v float64, ok bool)
+
+type pseudoRegistry struct {
+	mu    sync.RWMutex
+	args  map[string]PseudoArg
+	attrs map[string]MemberAttr
+}
+
+var pseudo = newPseudoRegistry()
+
+func newPseudoRegistry() *pseudoRegistry {
+	r := &pseudoRegistry{args: make(map[string]PseudoArg), attrs: make(map[string]MemberAttr)}
+	r.args["$wait"] = func(now int64, t *Ticket) (float64, bool) {
+		return float64(now - t.startMatch), true
+	}
+	r.args["$member"] = func(_ int64, t *Ticket) (float64, bool) {
+		return float64(len(t.Members)), true
+	}
+	r.args["$optional"] = func(_ int64, t *Ticket) (float64, bool) {
+		return float64(countMembers(t, func(m *Member) bool { return m.Sort > 0 })), true
+	}
+	r.args["$required"] = func(_ int64, t *Ticket) (float64, bool) {
+		return float64(countMembers(t, func(m *Member) bool { return m.Sort == 0 })), true
+	}
+	r.args["$blacklist_size"] = func(_ int64, t *Ticket) (float64, bool) {
+		return float64(len(t.BlackList)), true
+	}
+	// 当前时间在 UTC 时区的小时，0-23
+	r.args["$hour_of_day"] = func(now int64, _ *Ticket) (float64, bool) {
+		return float64(time.UnixMilli(now).UTC().Hour()), true
+	}
+	r.attrs["sort"] = func(m *Member) (float64, bool) {
+		return float64(m.Sort), true
+	}
+	return r
+}
+
+// RegisterPseudoArg 注册伪参数，name 需要以 $ 开头，同名时覆盖内置的伪参数
+func RegisterPseudoArg(name string, f PseudoArg) {
+	pseudo.mu.Lock()
+	defer pseudo.mu.Unlock()
+	pseudo.args[name] = f
+}
+
+// RegisterMemberAttr 注册队员级属性
+func RegisterMemberAttr(name string, f MemberAttr) {
+	pseudo.mu.Lock()
+	defer pseudo.mu.Unlock()
+	pseudo.attrs[name] = f
+}
+
+// lookupPseudo 查找伪参数，包括队员属性的聚合
+func lookupPseudo(name string) (PseudoArg, bool) {
+	pseudo.mu.RLock()
+	defer pseudo.mu.RUnlock()
+	if f, ok := pseudo.args[name]; ok {
+		return f, true
+	}
+	for _, agg := range []string{"$min.", "$max.", "$avg."} {
+		if attr, ok := strings.CutPrefix(name, agg); ok {
+			if f, ok := pseudo.attrs[attr]; ok {
+				return aggregate(agg, f), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func aggregate(agg string, attr MemberAttr) PseudoArg {
+	return func(_ int64, t *Ticket) (float64, bool) {
+		lo, hi, sum, n := math.Inf(1), math.Inf(-1), 0.0, 0
+		for i := range t.Members {
+			v, ok := attr(&t.Members[i])
+			if !ok {
+				continue
+			}
+			lo, hi, sum, n = min(lo, v), max(hi, v), sum+v, n+1
+		}
+		switch {
+		case n == 0:
+			return 0, false
+		case agg == "$min.":
+			return lo, true
+		case agg == "$max.":
+			return hi, true
+		}
+		return sum / float64(n), true
+	}
+}
+
+func countMembers(t *Ticket, f func(m *Member) bool) int {
+	n := 0
+	for i := range t.Members {
+		if f(&t.Members[i]) {
+			n++
+		}
+	}
+	return n
+}
+
+func isPseudo(arg string) bool {
+	return strings.HasPrefix(arg, "$")
+}
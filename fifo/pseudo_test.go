@@ -0,0 +1,65 @@
+package fifo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PseudoArgs(t *testing.T) {
+	// 在独立的注册表上注册测试用的伪参数，结束后恢复
+	defer func(r *pseudoRegistry) { pseudo = r }(pseudo)
+	pseudo = newPseudoRegistry()
+
+	ti := &Ticket{
+		Members:   []Member{{MemberId: "1"}, {MemberId: "2", Sort: 1}, {MemberId: "3", Sort: 3}},
+		BlackList: []int64{1, 2},
+	}
+	now := time.Date(2024, 1, 1, 21, 30, 0, 0, time.UTC).UnixMilli()
+	for arg, want := range map[string]float64{
+		"$member":         3,
+		"$optional":       2,
+		"$required":       1,
+		"$blacklist_size": 2,
+		"$hour_of_day":    21,
+		"$min.sort":       0,
+		"$max.sort":       3,
+		"$avg.sort":       4.0 / 3,
+	} {
+		f, ok := lookupPseudo(arg)
+		assert.True(t, ok, arg)
+		v, ok := f(now, ti)
+		assert.True(t, ok, arg)
+		assert.Equal(t, want, v, arg)
+	}
+	_, ok := lookupPseudo("$sum.sort")
+	assert.False(t, ok)
+
+	RegisterPseudoArg("$test_party_ratio", func(_ int64, t *Ticket) (float64, bool) {
+		return float64(len(t.Members)) / 5, len(t.Members) > 0
+	})
+	RegisterMemberAttr("test_index", func(m *Member) (float64, bool) {
+		return float64(len(m.MemberId)), m.MemberId != "3"
+	})
+	p, err := ParseProfile([]byte(`{"pools": [{
+		"name": "a",
+		"int_filters": [{"arg": "$optional", "min": 0, "max": 2}],
+		"float_filters": [{"arg": "$test_party_ratio", "min": 0.5, "max": 1}],
+		"rule": "$max.test_index == 1 && $hour_of_day between 18..23",
+		"teams": ["a"], "team_members": 5, "max_match_per_round": 1
+	}]}`), "json")
+	assert.NoError(t, err)
+	pool, err := p.Pools[0].Compile()
+	assert.NoError(t, err)
+	assert.True(t, pool.Allow(now, ti))
+	assert.False(t, pool.Allow(now-12*int64(time.Hour/time.Millisecond), ti))
+	ti.Members = ti.Members[:2]
+	field, _ := pool.reject(now, ti)
+	assert.Equal(t, "float_filters[0]", field)
+
+	p.Pools[0].IntFilters[0].Arg = "$level"
+	var es ValidationErrors
+	assert.ErrorAs(t, p.Validate(), &es)
+	assert.Equal(t, "int_filters[0].arg", es[0].Field)
+}
@@ -0,0 +1,191 @@
+package fifo
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrProposalNotFound = errors.New("fifo: proposal not found")
+	ErrNotInProposal    = errors.New("fifo: ticket not in proposal")
+	ErrCooldown         = errors.New("fifo: member in cooldown")
+)
+
+// ReadyCheck 匹配确认流程：匹配结果先作为提议投递，所有 ticket 在 Timeout 内 Accept 后才真正成立。
+// 有 ticket 拒绝或超时未确认时提议取消，这些 ticket 结束为 StatusDeclined，
+// 其余 ticket 带着原有的开始匹配时间回到原匹配池继续排队。
+type ReadyCheck struct {
+	Timeout   time.Duration               // 确认期限
+	Cooldown  time.Duration               // 拒绝或超时的队员在此时间内不能重新 Enqueue，0 表示不限制
+	Confirmed ResultSubmitter             // 全部确认后投递匹配结果
+	Cancelled func(cancel ProposalCancel) // 提议取消时的通知，可以为 nil
+}
+
+// ProposalCancel 被取消的提议
+type ProposalCancel struct {
+	ProposalId string   `json:"proposal_id"`
+	Declined   []string `json:"declined"` // 拒绝或超时未确认的 ticket
+	Requeued   []string `json:"requeued"` // 回到匹配池的 ticket
+}
+
+// WithReadyCheck 开启匹配确认流程，ResultSubmitter 收到的结果带有 ProposalId，需要通过 Accept/Decline 确认
+func WithReadyCheck(rc ReadyCheck) Option {
+	return func(m *Matchmaker) {
+		m.ready = &rc
+	}
+}
+
+type proposal struct {
+	seq      uint64
+	result   MatchResult
+	deadline int64
+	tickets  map[string]*Ticket
+	pools    map[string]string // TicketId -> 指定的匹配池，自动路由的 ticket 为 ""
+	accepted map[string]bool
+}
+
+// propose 将本轮的匹配结果转为提议，调用前 ticket 仍在匹配池中
+func (m *Matchmaker) propose(r *MatchResult, now int64) {
+	m.seq++
+	r.ProposalId = strconv.FormatUint(m.seq, 10)
+	p := &proposal{
+		seq:      m.seq,
+		result:   *r,
+		deadline: now + m.ready.Timeout.Milliseconds(),
+		tickets:  make(map[string]*Ticket),
+		pools:    make(map[string]string),
+		accepted: make(map[string]bool),
+	}
+	for _, tr := range r.Teams {
+		for _, id := range tr.TicketId {
+			if t, ok := m.routed[id]; ok {
+				p.tickets[id] = t
+			}
+			for _, ps := range m.pools {
+				if t, ok := ps.tickets[id]; ok {
+					p.tickets[id], p.pools[id] = t, ps.profile.Name
+				}
+			}
+		}
+	}
+	m.proposals[r.ProposalId] = p
+}
+
+// Accept 确认提议，全部 ticket 确认后通过 ReadyCheck.Confirmed 投递匹配结果
+func (m *Matchmaker) Accept(proposalId, ticketId string) error {
+	m.mu.Lock()
+	p, err := m.proposal(proposalId, ticketId)
+	if err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	p.accepted[ticketId] = true
+	if len(p.accepted) < len(p.tickets) {
+		m.mu.Unlock()
+		return nil
+	}
+	delete(m.proposals, proposalId)
+	for id := range p.tickets {
+		m.store.setStatus(id, StatusMatched)
+	}
+	m.mu.Unlock()
+
+	if m.ready.Confirmed != nil {
+		m.ready.Confirmed(p.result)
+	}
+	return nil
+}
+
+// Decline 拒绝提议，提议立即取消
+func (m *Matchmaker) Decline(proposalId, ticketId string) error {
+	m.mu.Lock()
+	p, err := m.proposal(proposalId, ticketId)
+	if err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	now := m.clock.Now().UnixMilli()
+	cancel := m.cancelProposal(p, now, func(id string) bool { return id == ticketId })
+	m.mu.Unlock()
+
+	if m.ready.Cancelled != nil {
+		m.ready.Cancelled(cancel)
+	}
+	return nil
+}
+
+func (m *Matchmaker) proposal(proposalId, ticketId string) (*proposal, error) {
+	p, ok := m.proposals[proposalId]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProposalNotFound, proposalId)
+	}
+	if _, ok := p.tickets[ticketId]; !ok {
+		return nil, fmt.Errorf("%w: %q in %s", ErrNotInProposal, ticketId, proposalId)
+	}
+	return p, nil
+}
+
+// timeoutProposals 取消已过确认期限的提议，未确认的 ticket 视为拒绝，同时清理已结束的冷却
+func (m *Matchmaker) timeoutProposals(now int64) (cancels []ProposalCancel) {
+	var timeout []*proposal
+	for _, p := range m.proposals {
+		if now >= p.deadline {
+			timeout = append(timeout, p)
+		}
+	}
+	slices.SortFunc(timeout, func(a, b *proposal) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+	for _, p := range timeout {
+		cancels = append(cancels, m.cancelProposal(p, now, func(id string) bool { return !p.accepted[id] }))
+	}
+	for id, until := range m.cooldown {
+		if now >= until {
+			delete(m.cooldown, id)
+		}
+	}
+	return cancels
+}
+
+// cancelProposal 移除提议，declined 的 ticket 结束并进入冷却，其余 ticket 保持原开始匹配时间重新排队
+func (m *Matchmaker) cancelProposal(p *proposal, now int64, declined func(id string) bool) ProposalCancel {
+	delete(m.proposals, p.result.ProposalId)
+	cancel := ProposalCancel{ProposalId: p.result.ProposalId}
+	for _, tr := range p.result.Teams {
+		for _, id := range tr.TicketId {
+			t := p.tickets[id]
+			if declined(id) {
+				cancel.Declined = append(cancel.Declined, id)
+				m.store.setStatus(id, StatusDeclined)
+				if m.ready.Cooldown > 0 {
+					for _, mb := range t.Members {
+						m.cooldown[mb.MemberId] = now + m.ready.Cooldown.Milliseconds()
+					}
+				}
+				continue
+			}
+			cancel.Requeued = append(cancel.Requeued, id)
+			m.store.setStatus(id, StatusQueued)
+			if ps := m.pool(p.pools[id]); ps != nil {
+				ps.tickets[id] = t
+			} else {
+				m.routed[id] = t
+			}
+		}
+	}
+	return cancel
+}
+
+// cooling 检查 ticket 的队员是否在冷却中
+func (m *Matchmaker) cooling(t *Ticket, now int64) error {
+	for _, mb := range t.Members {
+		if until, ok := m.cooldown[mb.MemberId]; ok && now < until {
+			return fmt.Errorf("%w: %q until %d", ErrCooldown, mb.MemberId, until)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package fifo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ReadyCheck(t *testing.T) {
+	clock := newManualClock()
+	var proposals, confirmed []MatchResult
+	var cancels []ProposalCancel
+	mm, err := NewMatchmaker(MatchProfile{
+		Tick: "1s",
+		Pools: []PoolProfile{{
+			Name:             "duo",
+			Teams:            []string{"a"},
+			TeamMembers:      2,
+			MaxMatchPerRound: 10,
+		}},
+	}, func(r MatchResult) {
+		proposals = append(proposals, r)
+	}, WithClock(clock), WithReadyCheck(ReadyCheck{
+		Timeout:   10 * time.Second,
+		Cooldown:  time.Minute,
+		Confirmed: func(r MatchResult) { confirmed = append(confirmed, r) },
+		Cancelled: func(c ProposalCancel) { cancels = append(cancels, c) },
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, mm.Enqueue("duo", soloTicket("1", "1_1")))
+	start := clock.Now().UnixMilli()
+	assert.NoError(t, mm.Enqueue("", soloTicket("2", "2_1")))
+
+	clock.Advance(time.Second)
+	mm.Tick()
+	assert.Len(t, proposals, 1)
+	pid := proposals[0].ProposalId
+	info, _ := mm.Get("2")
+	assert.Equal(t, StatusProposed, info.Status)
+
+	// 拒绝后 2 带着原开始匹配时间重新排队，1 进入冷却
+	assert.ErrorIs(t, mm.Accept(pid, "3"), ErrNotInProposal)
+	assert.NoError(t, mm.Decline(pid, "1"))
+	assert.Equal(t, []ProposalCancel{{ProposalId: pid, Declined: []string{"1"}, Requeued: []string{"2"}}}, cancels)
+	info, _ = mm.Get("2")
+	assert.Equal(t, StatusQueued, info.Status)
+	assert.Equal(t, start, info.StartMatch)
+	info, _ = mm.Get("1")
+	assert.Equal(t, StatusDeclined, info.Status)
+	assert.ErrorIs(t, mm.Enqueue("duo", soloTicket("1b", "1_1")), ErrCooldown)
+	assert.ErrorIs(t, mm.Accept(pid, "2"), ErrProposalNotFound)
+
+	assert.NoError(t, mm.Enqueue("duo", soloTicket("3", "3_1")))
+	mm.Tick()
+	assert.Len(t, proposals, 2)
+	pid = proposals[1].ProposalId
+	assert.NoError(t, mm.Accept(pid, "2"))
+	assert.Empty(t, confirmed)
+	assert.NoError(t, mm.Accept(pid, "3"))
+	assert.Len(t, confirmed, 1)
+	assert.ElementsMatch(t, []string{"2", "3"}, confirmed[0].Teams[0].TicketId)
+	info, _ = mm.Get("3")
+	assert.Equal(t, StatusMatched, info.Status)
+
+	// 超时未确认视为拒绝
+	assert.NoError(t, mm.Enqueue("duo", soloTicket("4", "4_1")))
+	assert.NoError(t, mm.Enqueue("duo", soloTicket("5", "5_1")))
+	mm.Tick()
+	pid = proposals[2].ProposalId
+	assert.NoError(t, mm.Accept(pid, "4"))
+	clock.Advance(10 * time.Second)
+	mm.Tick()
+	assert.Equal(t, ProposalCancel{ProposalId: pid, Declined: []string{"5"}, Requeued: []string{"4"}}, cancels[1])
+	info, _ = mm.Get("4")
+	assert.Equal(t, StatusQueued, info.Status)
+
+	clock.Advance(time.Minute)
+	assert.NoError(t, mm.Enqueue("duo", soloTicket("1b", "1_1")))
+}
@@ -0,0 +1,133 @@
+package fifo
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+)
+
+// ReloadReport Reload 的变更报告
+type ReloadReport struct {
+	Added    []string `json:"added"`    // 新增的匹配池
+	Removed  []string `json:"removed"`  // 删除的匹配池
+	Changed  []string `json:"changed"`  // 配置发生变化的匹配池
+	Orphaned []string `json:"orphaned"` // 无法进入任何新匹配池而被移出的 ticket
+}
+
+// Reload 在两轮匹配之间替换匹配场配置，排队中的 ticket 保留开始匹配时间。
+// 指定匹配池的 ticket 在原池仍存在时留在原池，否则与自动路由的 ticket 一样按新配置重新路由；
+// 没有任何匹配池接纳的 ticket 以 ExpireNoPool 通过 ExpiredSubmitter 通知。
+func (m *Matchmaker) Reload(profile MatchProfile) (ReloadReport, error) {
+	profile, tick, algorithm, err := m.check(profile)
+	if err != nil {
+		return ReloadReport{}, err
+	}
+	m.mu.Lock()
+	now := m.clock.Now().UnixMilli()
+	var report ReloadReport
+	old := make(map[string]*poolState, len(m.pools))
+	for _, p := range m.pools {
+		old[p.profile.Name] = p
+	}
+	pools := make([]*poolState, 0, len(profile.Pools))
+	for _, pp := range profile.Pools {
+		p := &poolState{profile: pp, tickets: make(map[string]*Ticket)}
+		if o, ok := old[pp.Name]; ok {
+			if !samePool(o.profile, pp) {
+				report.Changed = append(report.Changed, pp.Name)
+			}
+			// 与 Enqueue 一致，指定匹配池的 ticket 留在原池，每轮再按新配置的 Allow 决定是否参与匹配
+			for id, t := range o.tickets {
+				t.endMatch = deadline(t, pp)
+				p.tickets[id] = t
+				delete(o.tickets, id)
+			}
+		} else {
+			report.Added = append(report.Added, pp.Name)
+		}
+		pools = append(pools, p)
+	}
+	for _, p := range m.pools {
+		if !containsPool(profile.Pools, p.profile.Name) {
+			report.Removed = append(report.Removed, p.profile.Name)
+		}
+	}
+
+	prev := m.pools
+	m.profile, m.algorithm, m.pools = profile, algorithm, pools
+	m.backfills = slices.DeleteFunc(m.backfills, func(b *Backfill) bool {
+		return !containsPool(profile.Pools, b.PoolName)
+	})
+	var orphaned []ExpiredTicket
+	orphan := func(t *Ticket, pool string) {
+		report.Orphaned = append(report.Orphaned, t.TicketId)
+		orphaned = append(orphaned, ExpiredTicket{
+			TicketId:   t.TicketId,
+			PoolName:   pool,
+			Reason:     ExpireNoPool,
+			StartMatch: t.startMatch,
+			EndMatch:   now,
+		})
+		m.store.setStatus(t.TicketId, StatusExpired)
+	}
+	for id, t := range m.routed {
+		if len(m.routeTicket(now, t)) == 0 {
+			delete(m.routed, id)
+			orphan(t, "")
+		}
+	}
+	// 留在旧匹配池中的 ticket 转为自动路由
+	for _, p := range prev {
+		for id, t := range p.tickets {
+			if len(m.routeTicket(now, t)) == 0 {
+				orphan(t, p.profile.Name)
+				continue
+			}
+			t.endMatch = deadline(t, PoolProfile{})
+			m.routed[id] = t
+		}
+	}
+	if tick != m.tick {
+		m.tick = tick
+		m.replaceTicker(tick)
+	}
+	m.mu.Unlock()
+
+	if m.expired != nil {
+		for _, e := range orphaned {
+			m.expired(e)
+		}
+	}
+	return report, nil
+}
+
+// deadline 计算 ticket 在匹配池中的截止时间，取 Ticket.MaxWait 与 PoolProfile.MaxWait 中较早者
+func deadline(t *Ticket, pool PoolProfile) int64 {
+	var end int64
+	if t.MaxWait > 0 {
+		end = t.startMatch + t.MaxWait
+	}
+	if w := pool.MaxWait; w > 0 && (end == 0 || t.startMatch+w < end) {
+		end = t.startMatch + w
+	}
+	return end
+}
+
+func containsPool(pools []PoolProfile, name string) bool {
+	for _, p := range pools {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// samePool 比较两个匹配池的可序列化配置，忽略 Bots 与编译产生的状态（函数之间无法比较）
+func samePool(a, b PoolProfile) bool {
+	x, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	y, err := json.Marshal(b)
+	return err == nil && bytes.Equal(x, y)
+}
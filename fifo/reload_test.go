@@ -0,0 +1,161 @@
+package fifo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Reload(t *testing.T) {
+	clock := newManualClock()
+	var (
+		results []MatchResult
+		expired []ExpiredTicket
+	)
+	profile := MatchProfile{
+		Tick: "1s",
+		Pools: []PoolProfile{{
+			Name:             "eu",
+			StringFilters:    []StringFilter{{Arg: "region", Op: EqualOp, Value: "eu"}},
+			Teams:            []string{"a"},
+			TeamMembers:      3,
+			MaxMatchPerRound: 10,
+		}, {
+			Name:             "duo",
+			Teams:            []string{"a"},
+			TeamMembers:      2,
+			MaxMatchPerRound: 10,
+		}},
+	}
+	mm, err := NewMatchmaker(profile, func(r MatchResult) {
+		results = append(results, r)
+	}, WithClock(clock), WithExpiredSubmitter(func(e ExpiredTicket) {
+		expired = append(expired, e)
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, mm.Enqueue("duo", soloTicket("1", "1_1")))
+	eu := soloTicket("2", "2_1")
+	eu.StringArgs = []StringArg{{"region", "eu"}}
+	assert.NoError(t, mm.Enqueue("", eu))
+	assert.NoError(t, mm.Start())
+	clock.Advance(time.Second)
+
+	// duo 改为单人池，eu 池删除，新增 us 池
+	next := MatchProfile{
+		Tick: "2s",
+		Pools: []PoolProfile{{
+			Name:             "duo",
+			Teams:            []string{"a"},
+			TeamMembers:      1,
+			MaxMatchPerRound: 10,
+		}, {
+			Name:             "us",
+			StringFilters:    []StringFilter{{Arg: "region", Op: EqualOp, Value: "us"}},
+			Teams:            []string{"a"},
+			TeamMembers:      1,
+			MaxMatchPerRound: 10,
+		}},
+	}
+	report, err := mm.Reload(next)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"us"}, report.Added)
+	assert.Equal(t, []string{"eu"}, report.Removed)
+	assert.Equal(t, []string{"duo"}, report.Changed)
+	assert.Empty(t, report.Orphaned)
+
+	_, err = mm.Reload(MatchProfile{Tick: "0"})
+	assert.ErrorIs(t, err, ErrInvalidTick)
+
+	// 新 tick 为 2s，1s 后不会触发匹配
+	clock.Advance(time.Second)
+	assert.Empty(t, results)
+	clock.Advance(time.Second)
+	mm.Stop()
+	assert.Len(t, results, 2)
+	info, _ := mm.Get("2")
+	assert.Equal(t, StatusMatched, info.Status)
+	assert.Equal(t, clock.Now().UnixMilli()-3000, info.StartMatch)
+
+	us := soloTicket("3", "3_1")
+	us.StringArgs = []StringArg{{"region", "us"}}
+	assert.NoError(t, mm.Enqueue("us", us))
+	next.Pools[0].StringFilters = []StringFilter{{Arg: "region", Op: EqualOp, Value: "eu"}}
+	report, err = mm.Reload(MatchProfile{Tick: "2s", Pools: next.Pools[:1]})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3"}, report.Orphaned)
+	assert.Equal(t, ExpireNoPool, expired[0].Reason)
+	assert.Equal(t, "us", expired[0].PoolName)
+}
+
+func Test_ReloadFromSubmitter(t *testing.T) {
+	clock := newManualClock()
+	profile := MatchProfile{
+		Tick: "1s",
+		Pools: []PoolProfile{{
+			Name:             "solo",
+			Teams:            []string{"a"},
+			TeamMembers:      1,
+			MaxMatchPerRound: 10,
+		}},
+	}
+	reloaded := make(chan error, 1)
+	var mm *Matchmaker
+	mm, err := NewMatchmaker(profile, func(r MatchResult) {
+		next := profile
+		next.Tick = "2s"
+		_, err := mm.Reload(next)
+		reloaded <- err
+	}, WithClock(clock))
+	assert.NoError(t, err)
+	assert.NoError(t, mm.Enqueue("", soloTicket("1", "1_1")))
+	assert.NoError(t, mm.Start())
+	clock.Advance(time.Second)
+	select {
+	case err := <-reloaded:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Reload blocked in ResultSubmitter")
+	}
+	mm.Stop()
+	assert.Equal(t, 2*time.Second, mm.tick)
+}
+
+func Test_samePool(t *testing.T) {
+	a := PoolProfile{
+		Name:             "a",
+		Teams:            []string{"a"},
+		TeamMembers:      2,
+		MaxMatchPerRound: 1,
+		Rule:             `region == "eu"`,
+		BotAfter:         1000,
+		Bots:             BotProviderFunc(func(BotRequest) []MemberResult { return nil }),
+	}
+	assert.True(t, samePool(a, a))
+	b := a
+	b.BotAfter = 2000
+	assert.False(t, samePool(a, b))
+}
+
+func Test_ReloadKeepsPinned(t *testing.T) {
+	profile := MatchProfile{
+		Tick: "1s",
+		Pools: []PoolProfile{{
+			Name:             "eu",
+			StringFilters:    []StringFilter{{Arg: "region", Op: EqualOp, Value: "eu"}},
+			Teams:            []string{"a"},
+			TeamMembers:      1,
+			MaxMatchPerRound: 10,
+		}},
+	}
+	mm, err := NewMatchmaker(profile, func(MatchResult) {}, WithClock(newManualClock()))
+	assert.NoError(t, err)
+	us := soloTicket("1", "1_1")
+	us.StringArgs = []StringArg{{"region", "us"}}
+	assert.NoError(t, mm.Enqueue("eu", us))
+	// 不满足过滤器的 ticket 在原池中等待，重新加载相同的配置不会移出
+	report, err := mm.Reload(profile)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Orphaned)
+	assert.Contains(t, mm.pool("eu").tickets, "1")
+}
@@ -0,0 +1,146 @@
+package fifo
+
+import (
+	"slices"
+	"sort"
+)
+
+// roleConflict candidate.allow 因角色无法分配而拒绝时的返回值
+const roleConflict = "role_quotas"
+
+// RoleQuota 每队需要的某个角色的人数
+type RoleQuota struct {
+	Role  string `json:"role"`
+	Count int    `json:"count"`
+}
+
+// roleSlots 一队 n 个角色位，RoleQuotas 之外的位置为 ""，任何队员都可以担任
+func roleSlots(quotas []RoleQuota, n int) []string {
+	slots := make([]string, 0, n)
+	for _, q := range quotas {
+		for range q.Count {
+			slots = append(slots, q.Role)
+		}
+	}
+	for len(slots) < n {
+		slots = append(slots, "")
+	}
+	return slots
+}
+
+func canPlay(m *Member, role string) bool {
+	return role == "" || slices.Contains(m.Roles, role)
+}
+
+// assignRoles 按 members 的顺序用增广路为队员分配角色位，返回每个队员的角色位下标，-1 表示未分配。
+// 增广不会让已分配的队员失去角色位，因此排在前面的队员优先被分配。
+func assignRoles(slots []string, members []Member) []int {
+	owner := make([]int, len(slots))
+	for i := range owner {
+		owner[i] = -1
+	}
+	assign := make([]int, len(members))
+	var seen []bool
+	var augment func(i int) bool
+	augment = func(i int) bool {
+		for s, role := range slots {
+			if seen[s] || !canPlay(&members[i], role) {
+				continue
+			}
+			seen[s] = true
+			if owner[s] < 0 || augment(owner[s]) {
+				owner[s] = i
+				return true
+			}
+		}
+		return false
+	}
+	for i := range members {
+		seen = make([]bool, len(slots))
+		augment(i)
+	}
+	for i := range assign {
+		assign[i] = -1
+	}
+	for s, i := range owner {
+		if i >= 0 {
+			assign[i] = s
+		}
+	}
+	return assign
+}
+
+// orderedMembers 队内所有队员，必选队员在前，可选队员按 Sort 升序
+func orderedMembers(tickets []*Ticket) []Member {
+	var ms []Member
+	for _, t := range tickets {
+		ms = append(ms, t.Members...)
+	}
+	sort.SliceStable(ms, func(i, j int) bool {
+		return ms[i].Sort < ms[j].Sort
+	})
+	return ms
+}
+
+// rolesFit 检查 t 加入后，队内所有必选队员能否分配到不同的角色位
+func (c *candidate) rolesFit(t *Ticket) bool {
+	var required []Member
+	for _, t2 := range append(c.tickets[:len(c.tickets):len(c.tickets)], t) {
+		for _, m := range t2.Members {
+			if m.Sort == 0 {
+				required = append(required, m)
+			}
+		}
+	}
+	slots := roleSlots(c.pool.RoleQuotas, c.size)
+	return !slices.Contains(assignRoles(slots, required), -1)
+}
+
+// rolesComplete 检查队伍能否填满所有指定角色的角色位且不少于 minSize 人，且必选队员都分配到角色位
+func (c *candidate) rolesComplete() bool {
+	if len(c.pool.RoleQuotas) == 0 {
+		return true
+	}
+	ms := orderedMembers(c.tickets)
+	slots := roleSlots(c.pool.RoleQuotas, c.size)
+	assign := assignRoles(slots, ms)
+	filled := make([]bool, len(slots))
+	n := 0
+	for i, s := range assign {
+		if s >= 0 {
+			filled[s] = true
+			n++
+		} else if ms[i].Sort == 0 {
+			return false
+		}
+	}
+	for s, role := range slots {
+		if role != "" && !filled[s] {
+			return false
+		}
+	}
+	return n >= c.minSize
+}
+
+// roleResult 按角色分配生成队员结果，未分配到角色位的可选队员被缩减，返回空余角色位的角色
+func (c *candidate) roleResult(tr *TeamResult) (free []string) {
+	ms := orderedMembers(c.tickets)
+	slots := roleSlots(c.pool.RoleQuotas, c.size)
+	filled := make([]bool, len(slots))
+	for i, s := range assignRoles(slots, ms) {
+		mr := MemberResult{MemberId: ms[i].MemberId, Extra: ms[i].Extra, sort: ms[i].Sort}
+		if s < 0 {
+			tr.CutMembers = append(tr.CutMembers, mr)
+			continue
+		}
+		mr.Role = slots[s]
+		filled[s] = true
+		tr.Members = append(tr.Members, mr)
+	}
+	for s, role := range slots {
+		if !filled[s] {
+			free = append(free, role)
+		}
+	}
+	return free
+}
@@ -0,0 +1,58 @@
+package fifo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_assignRoles(t *testing.T) {
+	slots := roleSlots([]RoleQuota{{"tank", 1}, {"healer", 1}}, 3)
+	assert.Equal(t, []string{"tank", "healer", ""}, slots)
+	// flex 先占 tank，tank 到来时增广让 flex 让出
+	ms := []Member{{Roles: []string{"tank", "healer"}}, {Roles: []string{"tank"}}, {Roles: []string{"dps"}}}
+	assert.Equal(t, []int{1, 0, 2}, assignRoles(slots, ms))
+	ms = []Member{{Roles: []string{"tank"}}, {Roles: []string{"tank"}}, {Roles: []string{"tank"}}}
+	assert.Equal(t, []int{2, 0, -1}, assignRoles(slots, ms))
+}
+
+func Test_FifoMatchRoles(t *testing.T) {
+	tickets := make(map[string]*Ticket)
+	for i, ti := range []*Ticket{
+		{TicketId: "t1", Members: []Member{{MemberId: "t1", Roles: []string{"tank"}}}},
+		{TicketId: "t2", Members: []Member{{MemberId: "t2", Roles: []string{"tank"}}}},
+		{TicketId: "d1", Members: []Member{{MemberId: "d1", Roles: []string{"dps"}}}},
+		{TicketId: "d2", Members: []Member{{MemberId: "d2", Roles: []string{"dps"}}}},
+		{TicketId: "f1", Members: []Member{{MemberId: "f1", Roles: []string{"dps", "healer"}}}},
+		{TicketId: "d3", Members: []Member{{MemberId: "d3", Roles: []string{"dps"}}}},
+	} {
+		ti.startMatch = int64(i)
+		tickets[ti.TicketId] = ti
+	}
+	pool := PoolProfile{
+		Teams:            []string{"a"},
+		TeamMembers:      5,
+		MaxMatchPerRound: 10,
+		RoleQuotas:       []RoleQuota{{"tank", 1}, {"healer", 1}, {"dps", 3}},
+	}
+	results, remaining, diags := Explain(pool, tickets, 0)
+	assert.Len(t, results, 1)
+	roles := make(map[string]string)
+	for _, m := range results[0].Teams[0].Members {
+		roles[m.MemberId] = m.Role
+	}
+	assert.Equal(t, map[string]string{"t1": "tank", "f1": "healer", "d1": "dps", "d2": "dps", "d3": "dps"}, roles)
+	assert.Equal(t, []*Ticket{tickets["t2"]}, remaining)
+	assert.Equal(t, ReasonNotEnough, diags[0].Reason)
+
+	delete(tickets, "f1")
+	tickets["d4"] = &Ticket{TicketId: "d4", Members: []Member{{MemberId: "d4", Roles: []string{"dps"}}}}
+	_, _, diags = Explain(pool, tickets, 0)
+	assert.Equal(t, ReasonRoles, reasons(diags)["t1"])
+
+	p := MatchProfile{Pools: []PoolProfile{pool}}
+	p.Pools[0].RoleQuotas = append(p.Pools[0].RoleQuotas, RoleQuota{"tank", 1})
+	var es ValidationErrors
+	assert.ErrorAs(t, p.Validate(), &es)
+	assert.Len(t, es, 2)
+}
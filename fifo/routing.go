@@ -0,0 +1,72 @@
+package fifo
+
+// route 计算本轮每个匹配池参与匹配的 ticket 集合，与 m.pools 一一对应。
+// 指定匹配池的 ticket 同样需要通过该池的 Allow，未通过时留在池中等待下一轮，直到超时。
+// 集合中的 ticket 已加上该池的 PartyArgs 聚合参数，补充与匹配使用相同的值。diags 不为 nil 时记录被过滤器拒绝的 ticket。
+func (m *Matchmaker) route(now int64, diags map[string][]Diagnosis) []map[string]*Ticket {
+	filtered := func(t *Ticket, p *poolState) {
+		if diags == nil {
+			return
+		}
+		if field, ok := p.profile.reject(now, t); !ok {
+			diags[t.TicketId] = append(diags[t.TicketId], Diagnosis{
+				TicketId: t.TicketId,
+				PoolName: p.profile.Name,
+				Tick:     now,
+				Reason:   ReasonFiltered,
+				Detail:   field,
+			})
+		}
+	}
+	sets := make([]map[string]*Ticket, len(m.pools))
+	for i, p := range m.pools {
+		sets[i] = make(map[string]*Ticket, len(p.tickets))
+		for id, t := range p.tickets {
+			if p.profile.Allow(now, t) {
+				sets[i][id] = p.profile.party(t)
+			} else {
+				filtered(t, p)
+			}
+		}
+	}
+	for id, t := range m.routed {
+		idx := m.routeTicket(now, t)
+		for _, i := range idx {
+			sets[i][id] = m.pools[i].profile.party(t)
+		}
+		// 记录路由过程中被尝试过的匹配池的拒绝原因
+		last := len(m.pools) - 1
+		if len(idx) > 0 {
+			last = idx[len(idx)-1]
+		}
+		for _, p := range m.pools[:last+1] {
+			filtered(t, p)
+		}
+	}
+	return sets
+}
+
+// routeTicket 按 MatchProfile.Routing 计算 ticket 当前所在的匹配池下标
+func (m *Matchmaker) routeTicket(now int64, t *Ticket) (idx []int) {
+	var elapsed int64
+	for i, p := range m.pools {
+		if !p.profile.Allow(now, t) {
+			continue
+		}
+		switch m.profile.Routing {
+		case RouteAll:
+			idx = append(idx, i)
+		case RouteFallthrough:
+			// 等待时间跨过本池的落入阈值时继续尝试下一个池，没有下一个池则留在最后一个
+			idx = append(idx[:0], i)
+			after := p.profile.FallthroughAfter
+			if after <= 0 || now-t.startMatch < elapsed+after {
+				return idx
+			}
+			elapsed += after
+		default:
+			return []int{i}
+		}
+	}
+	return idx
+}
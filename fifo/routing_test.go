@@ -0,0 +1,125 @@
+package fifo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func routingProfile(routing string) MatchProfile {
+	return MatchProfile{
+		Tick:    "1s",
+		Routing: routing,
+		Pools: []PoolProfile{{
+			Name:             "duo",
+			StringFilters:    []StringFilter{{Arg: "mode", Op: EqualOp, Value: "duo"}},
+			Teams:            []string{"a"},
+			TeamMembers:      2,
+			MaxMatchPerRound: 10,
+			FallthroughAfter: 10000,
+		}, {
+			Name:             "solo",
+			Teams:            []string{"a"},
+			TeamMembers:      1,
+			MaxMatchPerRound: 10,
+		}},
+	}
+}
+
+func Test_routeTicket(t *testing.T) {
+	duo := soloTicket("1", "1_1")
+	duo.StringArgs = []StringArg{{"mode", "duo"}}
+	solo := soloTicket("2", "2_1")
+
+	mm, err := NewMatchmaker(routingProfile(""), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0}, mm.routeTicket(0, duo))
+	assert.Equal(t, []int{1}, mm.routeTicket(0, solo))
+
+	mm, _ = NewMatchmaker(routingProfile(RouteAll), nil)
+	assert.Equal(t, []int{0, 1}, mm.routeTicket(0, duo))
+	assert.Equal(t, []int{1}, mm.routeTicket(0, solo))
+
+	mm, _ = NewMatchmaker(routingProfile(RouteFallthrough), nil)
+	assert.Equal(t, []int{0}, mm.routeTicket(9999, duo))
+	assert.Equal(t, []int{1}, mm.routeTicket(10000, duo))
+	assert.Equal(t, []int{1}, mm.routeTicket(0, solo))
+
+	_, err = NewMatchmaker(MatchProfile{Tick: "1s", Routing: "random"}, nil)
+	assert.ErrorIs(t, err, ErrUnknownRouting)
+}
+
+func Test_RouteAllExclusive(t *testing.T) {
+	clock := newManualClock()
+	var results []MatchResult
+	mm, err := NewMatchmaker(routingProfile(RouteAll), func(r MatchResult) {
+		results = append(results, r)
+	}, WithClock(clock))
+	assert.NoError(t, err)
+	for _, id := range []string{"1", "2"} {
+		ti := soloTicket(id, id+"_1")
+		ti.StringArgs = []StringArg{{"mode", "duo"}}
+		assert.NoError(t, mm.Enqueue("", ti))
+	}
+	assert.NoError(t, mm.Enqueue("", soloTicket("3", "3_1")))
+
+	clock.Advance(time.Second)
+	mm.Tick()
+	// 1、2 已被 duo 选中，solo 池只能看到 3
+	assert.Len(t, results, 2)
+	assert.Equal(t, "duo", results[0].PoolName)
+	assert.ElementsMatch(t, []string{"1", "2"}, results[0].Teams[0].TicketId)
+	assert.Equal(t, "solo", results[1].PoolName)
+	assert.Equal(t, []string{"3"}, results[1].Teams[0].TicketId)
+	assert.Empty(t, mm.routed)
+}
+
+func Test_RouteAllLeftover(t *testing.T) {
+	clock := newManualClock()
+	var results []MatchResult
+	mm, err := NewMatchmaker(routingProfile(RouteAll), func(r MatchResult) {
+		results = append(results, r)
+	}, WithClock(clock))
+	assert.NoError(t, err)
+	ti := soloTicket("1", "1_1")
+	ti.StringArgs = []StringArg{{"mode", "duo"}}
+	assert.NoError(t, mm.Enqueue("", ti))
+
+	clock.Advance(time.Second)
+	mm.Tick()
+	// duo 池凑不齐两人，1 仍可被 solo 池选中
+	assert.Len(t, results, 1)
+	assert.Equal(t, "solo", results[0].PoolName)
+	assert.Equal(t, []string{"1"}, results[0].Teams[0].TicketId)
+}
+
+func Test_PinnedFiltered(t *testing.T) {
+	clock := newManualClock()
+	var (
+		results []MatchResult
+		expired []ExpiredTicket
+	)
+	profile := routingProfile("")
+	profile.Pools[0].MaxWait = 2000
+	mm, err := NewMatchmaker(profile, func(r MatchResult) {
+		results = append(results, r)
+	}, WithClock(clock), WithExpiredSubmitter(func(e ExpiredTicket) {
+		expired = append(expired, e)
+	}))
+	assert.NoError(t, err)
+	// 两个 ticket 都不满足 duo 池的过滤器，不参与匹配
+	assert.NoError(t, mm.Enqueue("duo", soloTicket("1", "1_1")))
+	assert.NoError(t, mm.Enqueue("duo", soloTicket("2", "2_1")))
+	clock.Advance(time.Second)
+	mm.Tick()
+	assert.Empty(t, results)
+	info, _ := mm.Get("1")
+	assert.Equal(t, StatusQueued, info.Status)
+
+	clock.Advance(time.Second)
+	mm.Tick()
+	assert.Empty(t, results)
+	assert.Len(t, expired, 2)
+	assert.Equal(t, ExpirePoolTimeout, expired[0].Reason)
+}
@@ -0,0 +1,480 @@
+package fifo
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// RuleError PoolProfile.Rule 的语法或类型错误，Line、Col 从 1 开始
+type RuleError struct {
+	Line, Col int
+	Msg       string
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+// compileRule 解析并类型检查规则文本，编译为与 FilterExpr 相同的 predicate。语法：
+//
+//	expr   = and { "||" and }
+//	and    = unary { "&&" unary }
+//	unary  = "!" unary | "(" expr ")" | cmp
+//	cmp    = ident ("==" | "!=") literal
+//	       | ident ("<" | "<=" | ">" | ">=") number
+//	       | ident ["not"] "in" "(" literal { "," literal } ")"
+//	       | ident "between" number ".." number
+//
+// ident 为 ticket 参数名或以 $ 开头的伪参数（见 RegisterPseudoArg），由字面量的类型决定查找 StringArgs、IntArgs 还是 FloatArgs：
+// 字符串查找 StringArgs，数字先查找与其类型相同的参数，找不到时再查找另一种数字参数。
+// 参数不存在时比较结果为假，!= 与 not in 除外。
+func compileRule(src string) (predicate, error) {
+	p := &ruleParser{lex: ruleLexer{src: src, line: 1, col: 1}}
+	p.next()
+	pred := p.parseOr()
+	if p.err == nil && p.tok.kind != tokEOF {
+		p.fail(p.tok, "unexpected %s", p.tok)
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return pred, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokInt
+	tokFloat
+	tokOp // 运算符与括号，text 为运算符本身
+)
+
+type ruleToken struct {
+	kind      tokenKind
+	text      string
+	line, col int
+}
+
+func (t ruleToken) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of rule"
+	case tokString:
+		return "string " + t.text
+	}
+	return strconv.Quote(t.text)
+}
+
+type ruleLexer struct {
+	src       string
+	pos       int
+	line, col int
+}
+
+func (l *ruleLexer) peek(i int) byte {
+	if l.pos+i < len(l.src) {
+		return l.src[l.pos+i]
+	}
+	return 0
+}
+
+func (l *ruleLexer) advance(n int) {
+	for range n {
+		if l.src[l.pos] == '\n' {
+			l.line, l.col = l.line+1, 1
+		} else {
+			l.col++
+		}
+		l.pos++
+	}
+}
+
+// scan 返回下一个 token，非法字符返回错误
+func (l *ruleLexer) scan() (ruleToken, *RuleError) {
+	for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+		l.advance(1)
+	}
+	tok := ruleToken{line: l.line, col: l.col}
+	start := l.pos
+	c := l.peek(0)
+	switch {
+	case c == 0:
+		tok.kind = tokEOF
+		return tok, nil
+	case c == '$' || c == '_' || isLetter(c):
+		l.advance(1)
+		// . 只出现在伪参数名中，如 $max.sort
+		for isLetter(l.peek(0)) || isDigit(l.peek(0)) || l.peek(0) == '_' || c == '$' && l.peek(0) == '.' && l.peek(1) != '.' {
+			l.advance(1)
+		}
+		tok.kind = tokIdent
+	case isDigit(c) || c == '-' && isDigit(l.peek(1)):
+		l.advance(1)
+		tok.kind = tokInt
+		for isDigit(l.peek(0)) || l.peek(0) == '.' && isDigit(l.peek(1)) {
+			if l.peek(0) == '.' {
+				tok.kind = tokFloat
+			}
+			l.advance(1)
+		}
+	case c == '"':
+		l.advance(1)
+		for l.peek(0) != '"' {
+			switch l.peek(0) {
+			case 0, '\n':
+				return tok, &RuleError{tok.line, tok.col, "unterminated string"}
+			case '\\':
+				l.advance(1)
+			}
+			l.advance(1)
+		}
+		l.advance(1)
+		tok.kind = tokString
+	default:
+		tok.kind = tokOp
+		for _, op := range []string{"&&", "||", "==", "!=", "<=", ">=", "..", "!", "<", ">", "(", ")", ","} {
+			if strings.HasPrefix(l.src[l.pos:], op) {
+				l.advance(len(op))
+				tok.text = op
+				return tok, nil
+			}
+		}
+		return tok, &RuleError{tok.line, tok.col, fmt.Sprintf("unexpected character %q", c)}
+	}
+	tok.text = l.src[start:l.pos]
+	return tok, nil
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+type ruleParser struct {
+	lex ruleLexer
+	tok ruleToken
+	err *RuleError
+}
+
+func (p *ruleParser) next() {
+	if p.err != nil {
+		return
+	}
+	tok, err := p.lex.scan()
+	if err != nil {
+		p.err = err
+		tok.kind = tokEOF
+	}
+	p.tok = tok
+}
+
+// fail 只记录第一个错误，之后的解析结果不再使用
+func (p *ruleParser) fail(at ruleToken, format string, args ...any) {
+	if p.err == nil {
+		p.err = &RuleError{at.line, at.col, fmt.Sprintf(format, args...)}
+	}
+}
+
+func (p *ruleParser) is(op string) bool {
+	return p.tok.kind == tokOp && p.tok.text == op || p.tok.kind == tokIdent && p.tok.text == op
+}
+
+func (p *ruleParser) expect(op string) {
+	if !p.is(op) {
+		p.fail(p.tok, "expected %q, got %s", op, p.tok)
+		return
+	}
+	p.next()
+}
+
+func (p *ruleParser) parseOr() predicate {
+	ps := []predicate{p.parseAnd()}
+	for p.err == nil && p.is("||") {
+		p.next()
+		ps = append(ps, p.parseAnd())
+	}
+	if len(ps) == 1 {
+		return ps[0]
+	}
+	return func(now int64, t *Ticket) bool {
+		for _, f := range ps {
+			if f(now, t) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (p *ruleParser) parseAnd() predicate {
+	ps := []predicate{p.parseUnary()}
+	for p.err == nil && p.is("&&") {
+		p.next()
+		ps = append(ps, p.parseUnary())
+	}
+	if len(ps) == 1 {
+		return ps[0]
+	}
+	return func(now int64, t *Ticket) bool {
+		for _, f := range ps {
+			if !f(now, t) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func (p *ruleParser) parseUnary() predicate {
+	switch {
+	case p.is("!"):
+		p.next()
+		f := p.parseUnary()
+		return func(now int64, t *Ticket) bool { return !f(now, t) }
+	case p.is("("):
+		p.next()
+		f := p.parseOr()
+		p.expect(")")
+		return f
+	}
+	return p.parseCmp()
+}
+
+// ruleValue 字面量，kind 为 tokString、tokInt 或 tokFloat
+type ruleValue struct {
+	kind tokenKind
+	s    string
+	i    int64
+	f    float64
+}
+
+func (p *ruleParser) parseLiteral() ruleValue {
+	tok := p.tok
+	v := ruleValue{kind: tok.kind}
+	var err error
+	switch tok.kind {
+	case tokString:
+		v.s, err = strconv.Unquote(tok.text)
+	case tokInt:
+		v.i, err = strconv.ParseInt(tok.text, 10, 64)
+		v.f = float64(v.i)
+	case tokFloat:
+		v.f, err = strconv.ParseFloat(tok.text, 64)
+	default:
+		p.fail(tok, "expected literal, got %s", tok)
+		return v
+	}
+	if err != nil {
+		p.fail(tok, "invalid literal %s", tok.text)
+	}
+	p.next()
+	return v
+}
+
+func (p *ruleParser) parseNumber() ruleValue {
+	tok := p.tok
+	v := p.parseLiteral()
+	if p.err == nil && v.kind == tokString {
+		p.fail(tok, "expected number, got %s", tok)
+	}
+	return v
+}
+
+func (p *ruleParser) parseCmp() predicate {
+	id := p.tok
+	if id.kind != tokIdent {
+		p.fail(id, "expected argument name, got %s", id)
+		return nil
+	}
+	p.next()
+	op := p.tok
+	switch {
+	case p.is("==") || p.is("!="):
+		p.next()
+		v := p.parseLiteral()
+		if p.err != nil {
+			return nil
+		}
+		arg := p.arg(id, v.kind, op)
+		if op.text == "==" {
+			return func(now int64, t *Ticket) bool {
+				x, ok := arg(now, t)
+				return ok && x.equal(v)
+			}
+		}
+		return func(now int64, t *Ticket) bool {
+			x, ok := arg(now, t)
+			return !ok || !x.equal(v)
+		}
+	case p.is("<") || p.is("<=") || p.is(">") || p.is(">="):
+		p.next()
+		v := p.parseNumber()
+		if p.err != nil {
+			return nil
+		}
+		arg := p.arg(id, v.kind, op)
+		cmp := map[string]func(a, b float64) bool{
+			"<":  func(a, b float64) bool { return a < b },
+			"<=": func(a, b float64) bool { return a <= b },
+			">":  func(a, b float64) bool { return a > b },
+			">=": func(a, b float64) bool { return a >= b },
+		}[op.text]
+		if v.kind == tokInt {
+			// 整数之间直接比较，避免超过 2^53 时的精度损失
+			icmp := map[string]func(a, b int64) bool{
+				"<":  func(a, b int64) bool { return a < b },
+				"<=": func(a, b int64) bool { return a <= b },
+				">":  func(a, b int64) bool { return a > b },
+				">=": func(a, b int64) bool { return a >= b },
+			}[op.text]
+			return func(now int64, t *Ticket) bool {
+				x, ok := arg(now, t)
+				if ok && x.kind == tokInt {
+					return icmp(x.i, v.i)
+				}
+				return ok && cmp(x.f, v.f)
+			}
+		}
+		return func(now int64, t *Ticket) bool {
+			x, ok := arg(now, t)
+			return ok && cmp(x.f, v.f)
+		}
+	case p.is("in") || p.is("not"):
+		not := p.is("not")
+		p.next()
+		if not {
+			p.expect("in")
+		}
+		p.expect("(")
+		var vs []ruleValue
+		for p.err == nil {
+			at := p.tok
+			v := p.parseLiteral()
+			if len(vs) > 0 && (v.kind == tokString) != (vs[0].kind == tokString) {
+				p.fail(at, "mixed string and number in list")
+			}
+			vs = append(vs, v)
+			if !p.is(",") {
+				break
+			}
+			p.next()
+		}
+		p.expect(")")
+		if p.err != nil {
+			return nil
+		}
+		arg := p.arg(id, vs[0].kind, op)
+		in := func(x ruleValue) bool {
+			return slices.ContainsFunc(vs, x.equal)
+		}
+		if not {
+			return func(now int64, t *Ticket) bool {
+				x, ok := arg(now, t)
+				return !ok || !in(x)
+			}
+		}
+		return func(now int64, t *Ticket) bool {
+			x, ok := arg(now, t)
+			return ok && in(x)
+		}
+	case p.is("between"):
+		p.next()
+		lo := p.parseNumber()
+		p.expect("..")
+		at := p.tok
+		hi := p.parseNumber()
+		if p.err == nil && lo.f > hi.f {
+			p.fail(at, "empty range %s..%s", lo.String(), hi.String())
+		}
+		if p.err != nil {
+			return nil
+		}
+		kind := tokInt
+		if lo.kind == tokFloat || hi.kind == tokFloat {
+			kind = tokFloat
+		}
+		arg := p.arg(id, kind, op)
+		return func(now int64, t *Ticket) bool {
+			x, ok := arg(now, t)
+			if ok && x.kind == tokInt && kind == tokInt {
+				return x.i >= lo.i && x.i <= hi.i
+			}
+			return ok && x.f >= lo.f && x.f <= hi.f
+		}
+	}
+	p.fail(op, "expected comparison after %s, got %s", id, op)
+	return nil
+}
+
+func (v ruleValue) String() string {
+	switch v.kind {
+	case tokString:
+		return strconv.Quote(v.s)
+	case tokInt:
+		return strconv.FormatInt(v.i, 10)
+	}
+	return strconv.FormatFloat(v.f, 'g', -1, 64)
+}
+
+// equal 比较同为字符串或同为数字的两个值
+func (v ruleValue) equal(v2 ruleValue) bool {
+	if v.kind == tokString || v2.kind == tokString {
+		return v.kind == v2.kind && v.s == v2.s
+	}
+	if v.kind == tokInt && v2.kind == tokInt {
+		return v.i == v2.i
+	}
+	return v.f == v2.f
+}
+
+// arg 按字面量类型返回读取参数的函数，伪参数只能与数字比较
+func (p *ruleParser) arg(id ruleToken, kind tokenKind, op ruleToken) func(now int64, t *Ticket) (ruleValue, bool) {
+	name := id.text
+	if strings.HasPrefix(name, "$") {
+		if kind == tokString {
+			p.fail(op, "%s is a number, cannot compare with string", name)
+			return nil
+		}
+		f, ok := lookupPseudo(name)
+		if !ok {
+			p.fail(id, "unknown pseudo argument %s", name)
+			return nil
+		}
+		return func(now int64, t *Ticket) (ruleValue, bool) {
+			v, ok := f(now, t)
+			if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+				return ruleValue{kind: tokInt, i: int64(v), f: v}, ok
+			}
+			return ruleValue{kind: tokFloat, f: v}, ok
+		}
+	}
+	if kind == tokString {
+		return func(_ int64, t *Ticket) (ruleValue, bool) {
+			s, ok := findString(t.StringArgs, name)
+			return ruleValue{kind: tokString, s: s}, ok
+		}
+	}
+	intArg := func(t *Ticket) (ruleValue, bool) {
+		i, ok := findInt(t.IntArgs, name)
+		return ruleValue{kind: tokInt, i: i, f: float64(i)}, ok
+	}
+	floatArg := func(t *Ticket) (ruleValue, bool) {
+		f, ok := findFloat(t.FloatArgs, name)
+		return ruleValue{kind: tokFloat, f: f}, ok
+	}
+	first, second := intArg, floatArg
+	if kind == tokFloat {
+		first, second = floatArg, intArg
+	}
+	return func(_ int64, t *Ticket) (ruleValue, bool) {
+		if v, ok := first(t); ok {
+			return v, true
+		}
+		return second(t)
+	}
+}
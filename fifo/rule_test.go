@@ -0,0 +1,71 @@
+package fifo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_compileRule(t *testing.T) {
+	f, err := compileRule(`region in ("eu", "us") && mmr between 1000..1500 && $wait > 30000`)
+	assert.NoError(t, err)
+	ti := &Ticket{StringArgs: []StringArg{{"region", "eu"}}, IntArgs: []IntArg{{"mmr", 1200}}}
+	assert.False(t, f(30000, ti))
+	assert.True(t, f(30001, ti))
+	ti.IntArgs = []IntArg{{"mmr", 1501}}
+	assert.False(t, f(30001, ti))
+	// 整数字面量找不到 IntArg 时查找 FloatArg
+	ti.IntArgs, ti.FloatArgs = nil, []FloatArg{{"mmr", 1499.5}}
+	assert.True(t, f(30001, ti))
+
+	f, err = compileRule("!(platform == \"pc\" || crossplay == \"on\")\n|| kd >= 1.5 && $member <= 2")
+	assert.NoError(t, err)
+	assert.False(t, f(0, &Ticket{StringArgs: []StringArg{{"platform", "pc"}}}))
+	assert.True(t, f(0, &Ticket{StringArgs: []StringArg{{"platform", "ps"}}}))
+	assert.True(t, f(0, &Ticket{StringArgs: []StringArg{{"platform", "pc"}}, FloatArgs: []FloatArg{{"kd", 2}}, Members: make([]Member, 2)}))
+
+	f, err = compileRule(`ban not in ("chat") && mode != "duo"`)
+	assert.NoError(t, err)
+	assert.True(t, f(0, &Ticket{}))
+	assert.False(t, f(0, &Ticket{StringArgs: []StringArg{{"mode", "duo"}}}))
+
+	for src, msg := range map[string]string{
+		`region == `:                    "1:11: expected literal, got end of rule",
+		`$wait == "x"`:                  `1:7: $wait is a number, cannot compare with string`,
+		"mmr > 1 &&\n  region < \"eu\"": `2:12: expected number, got string "eu"`,
+		`region in ("eu", 1)`:           "1:18: mixed string and number in list",
+		`mmr between 5..1`:              "1:16: empty range 5..1",
+		`$level > 1`:                    "1:1: unknown pseudo argument $level",
+		`(mmr > 1`:                      `1:9: expected ")", got end of rule`,
+		`mmr > 1 # x`:                   `1:9: unexpected character '#'`,
+		`region == "eu`:                 "1:11: unterminated string",
+		`mmr > 1 mmr`:                   `1:9: unexpected "mmr"`,
+		`region ~ "eu"`:                 `1:8: unexpected character '~'`,
+		`region.x == "eu"`:              `1:7: unexpected character '.'`,
+		`region`:                        `1:7: expected comparison after "region", got end of rule`,
+	} {
+		_, err := compileRule(src)
+		assert.EqualError(t, err, msg, src)
+	}
+}
+
+func Test_PoolRule(t *testing.T) {
+	p, err := ParseProfile([]byte(`{"pools": [{
+		"name": "eu", "rule": "region == \"eu\"",
+		"teams": ["a"], "team_members": 1, "max_match_per_round": 1
+	}]}`), "json")
+	assert.NoError(t, err)
+	pool, err := p.Pools[0].Compile()
+	assert.NoError(t, err)
+	field, ok := pool.reject(0, &Ticket{StringArgs: []StringArg{{"region", "us"}}})
+	assert.False(t, ok)
+	assert.Equal(t, "rule", field)
+	// 未经 Compile 的配置同样生效
+	raw := PoolProfile{Name: "eu", Rule: `region == "eu"`}
+	assert.True(t, raw.Allow(0, &Ticket{StringArgs: []StringArg{{"region", "eu"}}}))
+
+	p.Pools[0].Rule = "region =="
+	assert.EqualError(t, p.Validate(), `pool "eu": rule: 1:10: expected literal, got end of rule`)
+	_, err = p.Pools[0].Compile()
+	assert.EqualError(t, err, `pool "eu": rule: 1:10: expected literal, got end of rule`)
+}
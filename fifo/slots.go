@@ -0,0 +1,142 @@
+package fifo
+
+import "fmt"
+
+// slotConflict candidate.allow 因 TeamSlot 过滤器拒绝时返回值的前缀
+const slotConflict = "team_slots["
+
+// TeamSlot 匹配结果中的一个队伍位置，各位置可以有不同的人数和过滤器，如 1v4、2v2v1
+type TeamSlot struct {
+	Name          string         `json:"name"`    // 对应 TeamResult.TeamName
+	Members       int            `json:"members"` // 该队人数
+	StringFilters []StringFilter `json:"string_filters"`
+	IntFilters    []IntFilter    `json:"int_filters"`
+	FloatFilters  []FloatFilter  `json:"float_filters"`
+}
+
+// Allow ticket 能否进入该位置，匹配池的过滤器之外额外检查
+func (s *TeamSlot) Allow(now int64, t *Ticket) bool {
+	_, ok := s.reject(now, t)
+	return ok
+}
+
+func (s *TeamSlot) reject(now int64, t *Ticket) (string, bool) {
+	return rejectFilters(now, t, s.StringFilters, s.IntFilters, s.FloatFilters)
+}
+
+// slotField 返回 slot 过滤器拒绝时的冲突约束，字段名与 Validate 一致
+func (c *candidate) slotField(field string) string {
+	for i := range c.pool.TeamSlots {
+		if &c.pool.TeamSlots[i] == c.slot {
+			return fmt.Sprintf("%s%d].%s", slotConflict, i, field)
+		}
+	}
+	return slotConflict + "]." + field
+}
+
+func teamName(pool PoolProfile, teams []*candidate, i int) string {
+	if teams[i].slot != nil {
+		return teams[i].slot.Name
+	}
+	return pool.Teams[i]
+}
+
+// slotMatch 使用 TeamSlots 时逐场组队：按 TeamSlots 的顺序为每个位置组出一队，
+// 各位置之间满足队间反亲和性，任一位置组不出队伍时本轮结束
+func slotMatch(pool PoolProfile, tickets map[string]*Ticket, now int64, r ResultSubmitter) {
+	n, total := 0, 0
+	for _, s := range pool.TeamSlots {
+		n = max(n, s.Members)
+		total += s.Members
+	}
+	if pool.diag != nil {
+		for _, t := range tickets {
+			if len(t.Members) > n {
+				pool.diag.record(t, ReasonTeamSize, "team_slots")
+			}
+		}
+	}
+	queue := sortTicket(tickets, n)
+	sum, bots := 0, false
+	for i := 1; i <= n; i++ {
+		sum += len(queue[i]) * i
+		for _, t := range queue[i] {
+			bots = bots || pool.botReady(now, t)
+		}
+	}
+	if sum < total && !bots {
+		for _, q := range queue {
+			for _, t := range q {
+				pool.diag.record(t, ReasonQuickFail, "")
+			}
+		}
+		return
+	}
+	matches := 0
+	for ; matches < pool.MaxMatchPerRound; matches++ {
+		teams, ok := buildSlots(&pool, queue, now)
+		if !ok {
+			break
+		}
+		r(matchResult(pool, pool.Balance.apply(teams)))
+		removeUsed(queue)
+	}
+	if pool.diag != nil && matches >= pool.MaxMatchPerRound {
+		for _, q := range queue {
+			for _, t := range q {
+				if !t.used {
+					pool.diag.recordIfAbsent(t, ReasonRoundLimit, "max_match_per_round")
+				}
+			}
+		}
+	}
+}
+
+// buildSlots 为每个位置组出一队，失败时释放已组出的队伍
+func buildSlots(pool *PoolProfile, queue [][]*Ticket, now int64) ([]*candidate, bool) {
+	teams := make([]*candidate, 0, len(pool.TeamSlots))
+	for i := range pool.TeamSlots {
+		c, ok := buildSlot(pool, &pool.TeamSlots[i], queue, now, teams)
+		if !ok {
+			for _, c := range teams {
+				for _, t := range c.tickets {
+					t.used = false
+				}
+			}
+			return nil, false
+		}
+		teams = append(teams, c)
+	}
+	return teams, true
+}
+
+// buildSlot 与 buildCandidates 相同地按 ticket 人数从多到少、等待时间从长到短选取队首，组出与 teams 不冲突的一队
+func buildSlot(pool *PoolProfile, slot *TeamSlot, queue [][]*Ticket, now int64, teams []*candidate) (*candidate, bool) {
+	for i := slot.Members; i >= 1; i-- {
+		for _, t := range queue[i] {
+			if t.used || !slot.Allow(now, t) {
+				continue
+			}
+			buf := newCandidate(pool, now)
+			buf.size, buf.minSize, buf.slot = slot.Members, slot.Members, slot
+			buf.join(t)
+			ok, conflict := search(buf, queue, slot.Members, pool.AllowCut)
+			if !ok && pool.fillWithBots(buf) {
+				ok = true
+			}
+			if !ok {
+				pool.diag.recordCandidate(buf, conflictReason(conflict), conflict)
+				continue
+			}
+			buf.tagAnti()
+			if buf.matchTeam(teams) {
+				buf.used = true
+				return buf, true
+			}
+			for _, t2 := range buf.tickets {
+				t2.used = false
+			}
+		}
+	}
+	return nil, false
+}
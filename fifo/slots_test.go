@@ -0,0 +1,47 @@
+package fifo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FifoMatchSlots(t *testing.T) {
+	pool := PoolProfile{
+		Name:             "hunt",
+		MaxMatchPerRound: 10,
+		TeamSlots: []TeamSlot{{
+			Name:          "hunter",
+			Members:       1,
+			StringFilters: []StringFilter{{Arg: "side", Op: EqualOp, Value: "hunter"}},
+		}, {
+			Name:          "survivor",
+			Members:       4,
+			StringFilters: []StringFilter{{Arg: "side", Op: EqualOp, Value: "survivor"}},
+		}},
+	}
+	assert.NoError(t, (&MatchProfile{Pools: []PoolProfile{pool}}).Validate())
+	tickets := make(map[string]*Ticket)
+	for i, side := range []string{"survivor", "survivor", "hunter", "survivor", "hunter", "survivor", "survivor"} {
+		id := string(rune('a' + i))
+		ti := soloTicket(id, id+"_1")
+		ti.StringArgs = []StringArg{{"side", side}}
+		ti.startMatch = int64(i)
+		tickets[id] = ti
+	}
+	results, remaining := Match(pool, tickets, 10)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "hunter", results[0].Teams[0].TeamName)
+	assert.Equal(t, []string{"c"}, results[0].Teams[0].TicketId)
+	assert.Equal(t, "survivor", results[0].Teams[1].TeamName)
+	assert.Equal(t, []string{"a", "b", "d", "f"}, results[0].Teams[1].TicketId)
+	assert.Equal(t, []*Ticket{tickets["e"], tickets["g"]}, remaining)
+
+	_, _, diags := Explain(pool, map[string]*Ticket{"e": tickets["e"], "g": tickets["g"]}, 10)
+	assert.Equal(t, ReasonQuickFail, reasons(diags)["e"])
+
+	pool.Teams, pool.TeamMembers = []string{"a"}, 4
+	var es ValidationErrors
+	assert.ErrorAs(t, (&MatchProfile{Pools: []PoolProfile{pool}}).Validate(), &es)
+	assert.Equal(t, "team_slots", es[0].Field)
+}
@@ -0,0 +1,209 @@
+package fifo
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidTicket   = errors.New("fifo: invalid ticket")
+	ErrDuplicateTicket = errors.New("fifo: duplicate ticket id")
+	ErrDuplicateMember = errors.New("fifo: member already queued")
+	ErrTicketNotFound  = errors.New("fifo: ticket not found")
+	ErrTicketNotQueued = errors.New("fifo: ticket not queued")
+)
+
+type TicketStatus int
+
+const (
+	StatusQueued    TicketStatus = iota + 1 // 排队中
+	StatusMatching                          // 正在参与一轮匹配
+	StatusMatched                           // 已匹配成功
+	StatusCancelled                         // 已取消
+	StatusExpired                           // 超时未匹配
+	StatusProposed                          // 已匹配，等待确认，见 WithReadyCheck
+	StatusDeclined                          // 拒绝或未在期限内确认匹配
+)
+
+func (s TicketStatus) String() string {
+	switch s {
+	case StatusQueued:
+		return "queued"
+	case StatusMatching:
+		return "matching"
+	case StatusMatched:
+		return "matched"
+	case StatusCancelled:
+		return "cancelled"
+	case StatusExpired:
+		return "expired"
+	case StatusProposed:
+		return "proposed"
+	case StatusDeclined:
+		return "declined"
+	}
+	return fmt.Sprintf("TicketStatus(%d)", int(s))
+}
+
+func (s TicketStatus) terminal() bool {
+	return s == StatusMatched || s == StatusCancelled || s == StatusExpired || s == StatusDeclined
+}
+
+// TicketArgs 可在排队过程中更新的 ticket 参数
+type TicketArgs struct {
+	StringArgs []StringArg `json:"string_args"`
+	IntArgs    []IntArg    `json:"int_args"`
+	FloatArgs  []FloatArg  `json:"float_args"`
+}
+
+// TicketInfo Get 返回的 ticket 快照
+type TicketInfo struct {
+	Ticket     Ticket
+	Status     TicketStatus
+	StartMatch int64 // 开始匹配时间，epoch 单位ms
+	EndMatch   int64 // 匹配截止时间，epoch 单位ms，0 表示不限
+	Finished   int64 // 进入结束状态的时间，epoch 单位ms，未结束为 0
+}
+
+type ticketEntry struct {
+	ticket   *Ticket
+	status   TicketStatus
+	finished int64
+}
+
+// TicketStore 管理 ticket 的生命周期，保证 TicketId 与 MemberId 在排队中的 ticket 间唯一。
+// TicketStore 不是并发安全的，Matchmaker 会在自己的锁内使用它。
+type TicketStore struct {
+	clock   Clock
+	tickets map[string]*ticketEntry
+	members map[string]string // MemberId -> TicketId，只记录未结束的 ticket
+}
+
+func NewTicketStore(clock Clock) *TicketStore {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return &TicketStore{
+		clock:   clock,
+		tickets: make(map[string]*ticketEntry),
+		members: make(map[string]string),
+	}
+}
+
+// Enqueue 加入排队并记录开始匹配时间，ticket 加入后由 store 持有，调用方不应再修改
+func (s *TicketStore) Enqueue(t *Ticket) error {
+	if t == nil || t.TicketId == "" || len(t.Members) == 0 {
+		return ErrInvalidTicket
+	}
+	if e, ok := s.tickets[t.TicketId]; ok && !e.status.terminal() {
+		return fmt.Errorf("%w: %q", ErrDuplicateTicket, t.TicketId)
+	}
+	seen := make(map[string]struct{}, len(t.Members))
+	for _, m := range t.Members {
+		if _, ok := seen[m.MemberId]; ok {
+			return fmt.Errorf("%w: %q", ErrDuplicateMember, m.MemberId)
+		}
+		seen[m.MemberId] = struct{}{}
+		if id, ok := s.members[m.MemberId]; ok {
+			return fmt.Errorf("%w: %q in ticket %q", ErrDuplicateMember, m.MemberId, id)
+		}
+	}
+	t.startMatch = s.clock.Now().UnixMilli()
+	t.endMatch = 0
+	if t.MaxWait > 0 {
+		t.endMatch = t.startMatch + t.MaxWait
+	}
+	t.used = false
+	s.tickets[t.TicketId] = &ticketEntry{ticket: t, status: StatusQueued}
+	for _, m := range t.Members {
+		s.members[m.MemberId] = t.TicketId
+	}
+	return nil
+}
+
+// Cancel 取消排队中的 ticket
+func (s *TicketStore) Cancel(ticketId string) error {
+	e, err := s.queued(ticketId)
+	if err != nil {
+		return err
+	}
+	s.finish(e, StatusCancelled)
+	return nil
+}
+
+// Update 替换排队中 ticket 的参数，开始匹配时间保持不变
+func (s *TicketStore) Update(ticketId string, args TicketArgs) error {
+	e, err := s.queued(ticketId)
+	if err != nil {
+		return err
+	}
+	e.ticket.StringArgs = args.StringArgs
+	e.ticket.IntArgs = args.IntArgs
+	e.ticket.FloatArgs = args.FloatArgs
+	return nil
+}
+
+func (s *TicketStore) Get(ticketId string) (TicketInfo, bool) {
+	e, ok := s.tickets[ticketId]
+	if !ok {
+		return TicketInfo{}, false
+	}
+	return TicketInfo{
+		Ticket:     *e.ticket,
+		Status:     e.status,
+		StartMatch: e.ticket.startMatch,
+		EndMatch:   e.ticket.endMatch,
+		Finished:   e.finished,
+	}, true
+}
+
+// Queued 返回所有排队中的 ticket，可直接交给 FifoMatch
+func (s *TicketStore) Queued() map[string]*Ticket {
+	r := make(map[string]*Ticket)
+	for id, e := range s.tickets {
+		if e.status == StatusQueued {
+			r[id] = e.ticket
+		}
+	}
+	return r
+}
+
+// Sweep 清理在 before 之前结束的 ticket 记录
+func (s *TicketStore) Sweep(before int64) {
+	for id, e := range s.tickets {
+		if e.status.terminal() && e.finished < before {
+			delete(s.tickets, id)
+		}
+	}
+}
+
+func (s *TicketStore) queued(ticketId string) (*ticketEntry, error) {
+	e, ok := s.tickets[ticketId]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrTicketNotFound, ticketId)
+	}
+	if e.status != StatusQueued {
+		return nil, fmt.Errorf("%w: %q is %s", ErrTicketNotQueued, ticketId, e.status)
+	}
+	return e, nil
+}
+
+func (s *TicketStore) setStatus(ticketId string, status TicketStatus) {
+	if e, ok := s.tickets[ticketId]; ok {
+		if status.terminal() {
+			s.finish(e, status)
+		} else {
+			e.status = status
+		}
+	}
+}
+
+func (s *TicketStore) finish(e *ticketEntry, status TicketStatus) {
+	e.status = status
+	e.finished = s.clock.Now().UnixMilli()
+	for _, m := range e.ticket.Members {
+		if s.members[m.MemberId] == e.ticket.TicketId {
+			delete(s.members, m.MemberId)
+		}
+	}
+}
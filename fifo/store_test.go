@@ -0,0 +1,51 @@
+package fifo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TicketStore(t *testing.T) {
+	clock := newManualClock()
+	s := NewTicketStore(clock)
+
+	assert.ErrorIs(t, s.Enqueue(&Ticket{TicketId: "0"}), ErrInvalidTicket)
+	assert.NoError(t, s.Enqueue(soloTicket("1", "a", "b")))
+	assert.ErrorIs(t, s.Enqueue(soloTicket("1", "c")), ErrDuplicateTicket)
+	assert.ErrorIs(t, s.Enqueue(soloTicket("2", "b")), ErrDuplicateMember)
+	assert.ErrorIs(t, s.Enqueue(soloTicket("3", "d", "d")), ErrDuplicateMember)
+
+	info, ok := s.Get("1")
+	assert.True(t, ok)
+	assert.Equal(t, StatusQueued, info.Status)
+	assert.Equal(t, clock.Now().UnixMilli(), info.StartMatch)
+
+	clock.Advance(time.Second)
+	assert.NoError(t, s.Update("1", TicketArgs{IntArgs: []IntArg{{"mmr", 1000}}}))
+	info, _ = s.Get("1")
+	assert.Equal(t, []IntArg{{"mmr", 1000}}, info.Ticket.IntArgs)
+	assert.Equal(t, clock.Now().UnixMilli()-1000, info.StartMatch)
+	assert.Len(t, s.Queued(), 1)
+
+	assert.NoError(t, s.Cancel("1"))
+	assert.ErrorIs(t, s.Cancel("1"), ErrTicketNotQueued)
+	assert.ErrorIs(t, s.Update("1", TicketArgs{}), ErrTicketNotQueued)
+	assert.ErrorIs(t, s.Cancel("9"), ErrTicketNotFound)
+	info, _ = s.Get("1")
+	assert.Equal(t, StatusCancelled, info.Status)
+	assert.Empty(t, s.Queued())
+
+	// 结束后 member 与 ticket id 都可以重新排队
+	assert.NoError(t, s.Enqueue(soloTicket("2", "b")))
+	assert.NoError(t, s.Enqueue(soloTicket("1", "a")))
+
+	s.setStatus("2", StatusMatched)
+	clock.Advance(time.Second)
+	s.Sweep(clock.Now().UnixMilli())
+	_, ok = s.Get("2")
+	assert.False(t, ok)
+	_, ok = s.Get("1")
+	assert.True(t, ok)
+}
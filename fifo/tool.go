@@ -2,6 +2,15 @@ package fifo
 
 import "slices"
 
+func intersects[T comparable](a, b []T) bool {
+	for _, x := range a {
+		if slices.Index(b, x) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func appendUnique[T comparable](a []T, x T) []T {
 	if slices.Index(a, x) < 0 {
 		return append(a, x)
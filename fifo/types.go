@@ -1,5 +1,7 @@
 package fifo
 
+import "regexp"
+
 type StringArg struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
@@ -17,13 +19,24 @@ type FloatArg struct {
 
 const (
 	EqualOp    = "="
-	NotEqualOp = "!="
+	NotEqualOp = "!="      // 没有该参数时通过
+	InOp       = "in"      // 值在 Values 中
+	NotInOp    = "not_in"  // 值不在 Values 中，没有该参数时通过
+	PrefixOp   = "prefix"  // 值以 Value 开头
+	SuffixOp   = "suffix"  // 值以 Value 结尾
+	RegexOp    = "regex"   // 值匹配正则表达式 Value
+	ExistsOp   = "exists"  // 有该参数
+	MissingOp  = "missing" // 没有该参数
 )
 
 type StringFilter struct {
-	Arg   string `json:"arg"`
-	Op    string `json:"op"`
-	Value string `json:"value"`
+	Arg     string   `json:"arg"`
+	Op      string   `json:"op"`
+	Value   string   `json:"value"`
+	Values  []string `json:"values"`  // InOp、NotInOp 使用
+	Members string   `json:"members"` // MembersAll 或 MembersAny 时检查队员的 StringArgs，为空时检查 ticket
+
+	re *regexp.Regexp // RegexOp 由 PoolProfile.Compile 编译
 }
 
 type IntFilter struct {
@@ -31,12 +44,28 @@ type IntFilter struct {
 	Min      int64   `json:"min"`
 	Max      int64   `json:"max"`
 	Excludes []int64 `json:"excludes"`
+	Members  string  `json:"members"` // MembersAll 或 MembersAny 时检查队员的 IntArgs，为空时检查 ticket
+	Relax    *Relax  `json:"relax"`   // 随等待时间放宽 Min/Max
+
+	pseudo PseudoArg // Arg 为伪参数时由 PoolProfile.Compile 解析
 }
 
 type FloatFilter struct {
-	Arg string  `json:"arg"`
-	Min float64 `json:"min"`
-	Max float64 `json:"max"`
+	Arg     string  `json:"arg"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	Members string  `json:"members"` // MembersAll 或 MembersAny 时检查队员的 FloatArgs，为空时检查 ticket
+	Relax   *Relax  `json:"relax"`   // 随等待时间放宽 Min/Max
+
+	pseudo PseudoArg // Arg 为伪参数时由 PoolProfile.Compile 解析
+}
+
+// Relax 放宽计划：ticket 每等待 Every 毫秒，Min 减少 Step、Max 增加 Step，累计放宽量不超过 Limit。
+// 如 MMR 范围 [1400, 1600]，每 10s 放宽 50，最多放宽 400，即从 ±100 逐步放宽到 ±500。
+type Relax struct {
+	Every int64   `json:"every"` // 放宽间隔，单位ms
+	Step  float64 `json:"step"`  // 每次放宽量
+	Limit float64 `json:"limit"` // 最大累计放宽量，0 表示不限
 }
 
 type Ticket struct {
@@ -46,21 +75,31 @@ type Ticket struct {
 	IntArgs    []IntArg    `json:"int_args"`
 	FloatArgs  []FloatArg  `json:"float_args"`
 	BlackList  []int64     `json:"black_list"` // 设置黑名单，不会跟指定 member 匹配到同team
+	WhiteList  []int64     `json:"white_list"` // 偏好的队友 BlackId，组队时优先尝试
+	MaxWait    int64       `json:"max_wait"`   // 最长等待时间，单位ms，0 表示不限
 	startMatch int64       // 开始匹配时间，epoch 单位ms
-	endMatch   int64       // 结束匹配时间，epoch 单位ms
+	endMatch   int64       // 结束匹配时间，epoch 单位ms，0 表示不限
 	used       bool
 }
 
 type Member struct {
-	MemberId string `json:"member_id"` // 整个匹配匹配场里 member 的全局唯一id
-	BlackId  int64  `json:"black_id"`  // 开启黑名单时，用这个值做检查
-	Extra    []byte `json:"extra"`     // 用户额外数据，随匹配结果透传
-	Sort     int    `json:"sort"`      // >0 表示该member是可选项，如果整个team匹配不成功可以丢弃该member匹配
+	MemberId   string      `json:"member_id"`   // 整个匹配匹配场里 member 的全局唯一id
+	BlackId    int64       `json:"black_id"`    // 开启黑名单时，用这个值做检查
+	Extra      []byte      `json:"extra"`       // 用户额外数据，随匹配结果透传
+	Sort       int         `json:"sort"`        // >0 表示该member是可选项，如果整个team匹配不成功可以丢弃该member匹配
+	Roles      []string    `json:"roles"`       // 可以担任的角色，配合 PoolProfile.RoleQuotas 使用
+	StringArgs []StringArg `json:"string_args"` // 队员级参数，配合 PoolProfile.PartyArgs 或过滤器的 Members 使用
+	IntArgs    []IntArg    `json:"int_args"`
+	FloatArgs  []FloatArg  `json:"float_args"`
 }
 
 type MatchResult struct {
-	PoolName string       `json:"pool_name"` // 表示从哪个池子挑出来的
-	Teams    []TeamResult `json:"teams"`     // 挑选出来的队伍
+	PoolName   string       `json:"pool_name"`   // 表示从哪个池子挑出来的
+	Teams      []TeamResult `json:"teams"`       // 挑选出来的队伍
+	Imbalance  float64      `json:"imbalance"`   // 开启 Balance 时，各队 Balance.Arg 最大值与最小值之差
+	BackfillId string       `json:"backfill_id"` // 补充运行中对局的结果，只包含补充的 ticket
+	ProposalId string       `json:"proposal_id"` // 开启 WithReadyCheck 时的提议id，用于 Accept/Decline
+	Preferred  int          `json:"preferred"`   // 满足的白名单与亲和性偏好数
 }
 
 type TeamResult struct {
@@ -68,34 +107,85 @@ type TeamResult struct {
 	TicketId   []string       `json:"ticket_id"` // 表示来源于哪些 Ticket
 	Members    []MemberResult `json:"members"`
 	CutMembers []MemberResult `json:"cut_members"`
+	Unfilled   int            `json:"unfilled"` // 开启 MinTeamMembers 时未满员的位置数
 }
 
 type MemberResult struct {
 	MemberId string `json:"member_id"` // 表示来源于Team中哪个 Member
 	Extra    []byte `json:"extra"`     // 从 Member 携带的 Extra
+	Role     string `json:"role"`      // 开启 RoleQuotas 时分配到的角色，"" 表示不限角色的位置
+	Bot      bool   `json:"bot"`       // 由 BotProvider 生成的机器人
 	sort     int
 }
 
 // MatchProfile 匹配场配置
 type MatchProfile struct {
 	Name      string        `json:"name"`      // 匹配场名字，唯一
-	Algorithm string        `json:"algorithm"` // 进行匹配所使用的算法，内置 fifo
+	Algorithm string        `json:"algorithm"` // 进行匹配所使用的算法，内置 fifo、mwm，为空时使用 fifo
 	Tick      string        `json:"tick"`      // 匹配场匹配频率，如 "0.5s"
-	Pools     []PoolProfile `json:"pools"`     // 匹配池
+	Routing   string        `json:"routing"`   // 未指定匹配池的 ticket 的路由方式，见 RouteFirst 等，为空时使用 RouteFirst
+	Pools     []PoolProfile `json:"pools"`     // 匹配池，路由时按顺序尝试
 }
 
+const (
+	RouteFirst       = "first"       // 进入第一个 Allow 的匹配池
+	RouteAll         = "all"         // 进入所有 Allow 的匹配池，先被哪个池选中即归属哪个池
+	RouteFallthrough = "fallthrough" // 按顺序进入 Allow 的匹配池，在池中等待超过 FallthroughAfter 后落入下一个
+)
+
 // PoolProfile 匹配池配置
 type PoolProfile struct {
-	Name                    string         `json:"name"`                       // 匹配池名字
-	BetweenTeamAntiAffinity string         `json:"between_team_anti_affinity"` // 队间反亲和性选择词
-	StringFilters           []StringFilter `json:"string_filters"`
-	IntFilters              []IntFilter    `json:"int_filters"`
-	FloatFilters            []FloatFilter  `json:"float_filters"`
-	Teams                   []string       `json:"teams"`               // 匹配结果需要多个team
-	TeamMembers             int            `json:"team_members"`        // 每队人数
-	MaxMatchPerRound        int            `json:"max_match_per_round"` // 每场最多匹配队伍
-	AllowCut                bool           `json:"allow_cut"`           // 允许缩减队伍
-	AllowHate               bool           `json:"allow_hate"`          // 考虑玩家的黑名单
+	Name                    string           `json:"name"`                       // 匹配池名字
+	BetweenTeamAntiAffinity string           `json:"between_team_anti_affinity"` // 队间反亲和性选择词
+	StringFilters           []StringFilter   `json:"string_filters"`
+	IntFilters              []IntFilter      `json:"int_filters"`
+	FloatFilters            []FloatFilter    `json:"float_filters"`
+	Filter                  *FilterExpr      `json:"filter"`              // 与上面的过滤器同时满足的过滤器表达式
+	Rule                    string           `json:"rule"`                // 文本形式的过滤规则，如 region in ("eu", "us") && $wait > 30000，语法见 compileRule
+	PartyArgs               []PartyArg       `json:"party_args"`          // 由队员参数聚合出的 ticket 参数
+	Teams                   []string         `json:"teams"`               // 匹配结果需要多个team
+	TeamMembers             int              `json:"team_members"`        // 每队人数
+	MaxMatchPerRound        int              `json:"max_match_per_round"` // 每场最多匹配队伍
+	AllowCut                bool             `json:"allow_cut"`           // 允许缩减队伍
+	AllowHate               bool             `json:"allow_hate"`          // 考虑玩家的黑名单
+	HateBetweenTeams        bool             `json:"hate_between_teams"`  // 同一场匹配的不同队伍之间也不能有互相拉黑的队员
+	AffinityArg             string           `json:"affinity_arg"`        // 亲和性选择词，值相同的 ticket 优先组入同队，其次同一场匹配
+	MaxWait                 int64            `json:"max_wait"`            // 池内最长等待时间，单位ms，0 表示不限
+	QualityArg              string           `json:"quality_arg"`         // mwm 算法计算队间匹配分使用的 IntArg/FloatArg，差值越小分越高
+	FallthroughAfter        int64            `json:"fallthrough_after"`   // RouteFallthrough 时在本池等待多久后落入下一个池，单位ms，0 表示不落入
+	PairConstraints         []PairConstraint `json:"pair_constraints"`    // 同队 ticket 间两两需要满足的约束
+	Balance                 *Balance         `json:"balance"`             // 多队匹配时按评分平衡各队
+	RoleQuotas              []RoleQuota      `json:"role_quotas"`         // 每队的角色人数要求，其余位置不限角色
+	TeamSlots               []TeamSlot       `json:"team_slots"`          // 各队人数或过滤器不同时使用，设置后不再使用 Teams 与 TeamMembers
+	MaxTeamMembers          int              `json:"max_team_members"`    // 每队最多人数，为 0 时使用 TeamMembers，与 TeamMembers 同时设置时需要相等
+	MinTeamMembers          int              `json:"min_team_members"`    // 每队最少人数，0 表示必须满员
+	MinTeamRelax            *Relax           `json:"min_team_relax"`      // 设置时最少人数从 MaxTeamMembers 随队首等待时间逐步放宽到 MinTeamMembers
+	BotAfter                int64            `json:"bot_after"`           // 队首等待超过该时间仍组不满时用机器人补齐，单位ms，0 表示不补齐
+	Bots                    BotProvider      `json:"-"`                   // 生成机器人，Matchmaker 中可通过 WithBotProvider 设置
+
+	diag     *diagnostics // 诊断模式下由匹配器设置
+	compiled bool         // 由 Compile 设置
+	filter   predicate    // Compile 时由 Filter 编译
+	rule     predicate    // Compile 时由 Rule 编译
 }
 
 type ResultSubmitter func(MatchResult)
+
+type ExpireReason string
+
+const (
+	ExpireTicketTimeout ExpireReason = "ticket_timeout" // 超过 Ticket.MaxWait
+	ExpirePoolTimeout   ExpireReason = "pool_timeout"   // 超过 PoolProfile.MaxWait
+	ExpireNoPool        ExpireReason = "no_pool"        // 重新加载配置后没有可进入的匹配池
+)
+
+// ExpiredTicket 超时离开匹配池的 ticket
+type ExpiredTicket struct {
+	TicketId   string       `json:"ticket_id"`
+	PoolName   string       `json:"pool_name"`
+	Reason     ExpireReason `json:"reason"`
+	StartMatch int64        `json:"start_match"` // 开始匹配时间，epoch 单位ms
+	EndMatch   int64        `json:"end_match"`   // 截止时间，epoch 单位ms
+}
+
+type ExpiredSubmitter func(ExpiredTicket)